@@ -1,48 +1,312 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/dvdk01/http-status-monitor/internal/agent"
+	"github.com/dvdk01/http-status-monitor/internal/alert"
 	"github.com/dvdk01/http-status-monitor/internal/application"
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/limiter"
+	"github.com/dvdk01/http-status-monitor/internal/maintenance"
 	"github.com/dvdk01/http-status-monitor/internal/monitor"
-	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/dvdk01/http-status-monitor/internal/resultlog"
+	"github.com/dvdk01/http-status-monitor/internal/retry"
+	"github.com/dvdk01/http-status-monitor/internal/state"
 
+	"github.com/dvdk01/http-status-monitor/internal/probe"
 	"github.com/dvdk01/http-status-monitor/internal/processor"
 	"github.com/dvdk01/http-status-monitor/internal/validator"
 )
 
 func printUsage(programName string) {
-	fmt.Fprintf(os.Stderr, "Usage: %s <url1> <url2> ... <urlN>\n", programName)
+	fmt.Fprintf(os.Stderr, "Usage: %s [--config file.json] [--state-file state.json] [--result-log dir] <url1> <url2> ... <urlN>\n", programName)
+	fmt.Fprintf(os.Stderr, "       %s server [--listen :8080] [--stale-after 1m]\n", programName)
+	fmt.Fprintf(os.Stderr, "       %s report --result-log dir [--since 24h] [--until now] [--target url] [--format table|json]\n", programName)
 }
 
 func main() {
-	args := os.Args[1:]
-	args = removeDuplicates(args)
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(runReport(os.Args[0], os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		os.Exit(runServer(os.Args[0], os.Args[2:]))
+	}
+	os.Exit(run(os.Args[0], os.Args[1:]))
+}
 
-	if len(args) == 0 {
-		printUsage(os.Args[0])
-		os.Exit(1)
+func run(programName string, args []string) int {
+	flags := flag.NewFlagSet(programName, flag.ExitOnError)
+	flags.Usage = func() {
+		printUsage(programName)
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", "", "path to a JSON config with targets, maintenance windows and alert rules")
+	stateFile := flags.String("state-file", "", "path to a stats snapshot to resume from and save to (alert state is not saved, so ongoing outages alert again after a restart)")
+	stateInterval := flags.Duration("state-interval", 30*time.Second, "how often to save the stats snapshot (must be positive)")
+	interval := flags.Duration("interval", 5*time.Second, "time between probes of targets without their own interval")
+	workers := flags.Int("workers", monitor.DefaultWorkers, "maximum number of probes in flight at the same time")
+	stagger := flags.Duration("stagger", 0, "spread the first probes of all targets over this window (defaults to --interval, 0 starts them all at once)")
+	renderInterval := flags.Duration("render-interval", 250*time.Millisecond, "redraw the table at most this often")
+	jitter := flags.Duration("jitter", 0, "randomly shift every probe by up to +/- this duration")
+	hostConcurrency := flags.Int("host-concurrency", 0, "maximum concurrent probes per URL host (0 is unlimited, overrides the config default)")
+	hostRate := flags.Float64("host-rps", 0, "maximum probes per second per URL host (0 is unlimited, overrides the config default)")
+	resultLogDir := flags.String("result-log", "", "directory to append every probe result to")
+	resultLogFormat := flags.String("result-log-format", "jsonl", "result log format: jsonl or binary")
+	resultLogMaxSize := flags.Int64("result-log-max-size", 64<<20, "rotate the result log after this many bytes (0 disables)")
+	resultLogMaxAge := flags.Duration("result-log-max-age", 24*time.Hour, "rotate the result log after this long (0 disables)")
+	resultLogMaxSegments := flags.Int("result-log-max-segments", 0, "keep at most this many rotated segments (0 keeps all)")
+	resultLogRetention := flags.Duration("result-log-retention", 7*24*time.Hour, "delete rotated segments older than this (0 keeps all)")
+	reportTo := flags.String("report-to", "", "URL of an http-status-monitor server to stream every probe result to")
+	agentName := flags.String("agent-name", "", "name of this agent on the server (defaults to the host name)")
+	flags.Parse(args) //nolint:errcheck
+	if !isFlagSet(flags, "stagger") {
+		*stagger = *interval
+	}
+	if *stateInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "--state-interval must be positive, got %v\n", *stateInterval)
+		return 1
 	}
 
-	results := validator.NewURLValidator().ValidateURLs(args)
+	cfg := &config.Config{}
+	if *configPath != "" {
+		loaded, err := config.Load(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		cfg = loaded
+	}
+	cfg.AddURLs(removeDuplicates(flags.Args()))
+
+	urls := cfg.URLs()
+	if len(urls) == 0 {
+		printUsage(programName)
+		return 1
+	}
+
+	results := validator.NewURLValidator().ValidateURLs(urls)
 
 	if validator.HasInvalidURLs(results) {
 		fmt.Fprintf(os.Stderr, "\nValidation failed: Some URLs are invalid\n")
 		fmt.Fprintf(os.Stderr, "Invalid URLs: %v\n", results.GetInvalidURLs())
 
-		printUsage(os.Args[0])
-		os.Exit(1)
+		printUsage(programName)
+		return 1
+	}
+
+	schedule, err := maintenance.New(cfg.Maintenance, cfg.Targets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	retries := make(map[string]retry.Policy)
+	intervals := make(map[string]monitor.IntervalPolicy)
+	bodies := make(map[string]probe.BodyPolicy)
+	connections := make(map[string]probe.ConnectionMode)
+	checks := make(map[string]string)
+	tcpPolicies := make(map[string]probe.TCPPolicy)
+	dnsPolicies := make(map[string]probe.DNSPolicy)
+	certPolicies := make(map[string]probe.CertPolicy)
+	messagePolicies := make(map[string]probe.MessagePolicy)
+	grpcTLS := make(map[string]bool)
+	graphQLPolicies := make(map[string]probe.GraphQLPolicy)
+	ssePolicies := make(map[string]probe.SSEPolicy)
+	for _, t := range cfg.Targets {
+		checks[t.URL] = t.Check
+		intervals[t.URL] = monitor.IntervalPolicy{
+			Base:        time.Duration(t.Interval),
+			Min:         time.Duration(t.Adaptive.MinInterval),
+			Max:         time.Duration(t.Adaptive.MaxInterval),
+			StableAfter: t.Adaptive.StableAfter,
+		}
+
+		policy, err := retry.New(t.Retry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "target %q: %v\n", t.URL, err)
+			return 1
+		}
+		retries[t.URL] = policy
+
+		body, err := probe.NewBodyPolicy(t.MaxBodySize, t.Content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "target %q: %v\n", t.URL, err)
+			return 1
+		}
+		bodies[t.URL] = body
+
+		mode, err := probe.ParseConnectionMode(t.Connection)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "target %q: %v\n", t.URL, err)
+			return 1
+		}
+		connections[t.URL] = mode
+
+		tcpPolicy, err := probe.NewTCPPolicy(t.TCP)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "target %q: %v\n", t.URL, err)
+			return 1
+		}
+		tcpPolicies[t.URL] = tcpPolicy
+		dnsPolicies[t.URL] = probe.NewDNSPolicy(t.DNS)
+		certPolicies[t.URL] = probe.NewCertPolicy(t.Cert)
+
+		messagePolicy, err := probe.NewMessagePolicy(t.WebSocket)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "target %q: %v\n", t.URL, err)
+			return 1
+		}
+		messagePolicies[t.URL] = messagePolicy
+		grpcTLS[t.URL] = t.GRPC.TLS
+		graphQLPolicies[t.URL] = probe.NewGraphQLPolicy(t.GraphQL)
+
+		ssePolicy, err := probe.NewSSEPolicy(t.SSE)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "target %q: %v\n", t.URL, err)
+			return 1
+		}
+		ssePolicies[t.URL] = ssePolicy
 	}
 
-	statsChan := make(chan map[string]*schema.URLStats)
-	defer close(statsChan)
+	probes := probe.NewRegistry()
+	probes.Register(probe.CheckHTTP, probe.NewHTTP(http.DefaultClient,
+		probe.WithBodyPolicies(bodies),
+		probe.WithConnectionModes(connections),
+		probe.WithCertPolicies(certPolicies),
+	))
+	probes.Register(probe.CheckTCP, probe.NewTCP(probe.WithTCPPolicies(tcpPolicies)))
+	probes.Register(probe.CheckTLS, probe.NewTLS(probe.WithTLSCertPolicies(certPolicies)))
+	probes.Register(probe.CheckTLSAudit, probe.NewTLSAudit(http.DefaultClient))
+	probes.Register(probe.CheckDNS, probe.NewDNS(probe.WithDNSPolicies(dnsPolicies)))
+	probes.Register(probe.CheckGRPC, probe.NewGRPC(probe.WithGRPCTLS(grpcTLS)))
+	probes.Register(probe.CheckGraphQL, probe.NewGraphQL(http.DefaultClient, probe.WithGraphQLPolicies(graphQLPolicies)))
+	probes.Register(probe.CheckSSE, probe.NewSSE(http.DefaultClient, probe.WithSSEPolicies(ssePolicies)))
+	probes.Register(probe.CheckWebSocket, probe.NewWebSocket(probe.WithMessagePolicies(messagePolicies)))
+
+	alerts := alert.NewManager(cfg.Alerts, cfg.Targets, schedule, alert.NewLogNotifier())
+
+	monitorOpts := []monitor.Option{
+		monitor.WithMaintenance(schedule),
+		monitor.WithDependencies(cfg.Dependencies()),
+		monitor.WithRetryPolicies(retries),
+		monitor.WithInterval(*interval),
+		monitor.WithIntervalPolicies(intervals),
+		monitor.WithProbes(probes),
+		monitor.WithCheckTypes(checks),
+		monitor.WithResultHandler(alerts.Observe),
+		monitor.WithWorkers(*workers),
+		monitor.WithStagger(*stagger),
+		monitor.WithJitter(*jitter),
+		monitor.WithRenderInterval(*renderInterval),
+		monitor.WithHostLimiter(newHostLimiter(cfg.HostLimits, *hostConcurrency, *hostRate)),
+	}
+	var processorOpts []processor.Option
+	if *stateFile != "" {
+		restored, err := state.Load(*stateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		monitorOpts = append(monitorOpts, monitor.WithInitialStats(restored))
+		processorOpts = append(processorOpts, processor.WithSnapshotter(state.NewSnapshotter(*stateFile, *stateInterval)))
+	}
+
+	if *resultLogDir != "" {
+		format, err := resultlog.ParseFormat(*resultLogFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		writer, err := resultlog.Open(resultlog.Options{
+			Dir:            *resultLogDir,
+			Format:         format,
+			MaxSegmentSize: *resultLogMaxSize,
+			MaxSegmentAge:  *resultLogMaxAge,
+			MaxSegments:    *resultLogMaxSegments,
+			Retention:      *resultLogRetention,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		defer writer.Close() //nolint
+		monitorOpts = append(monitorOpts, monitor.WithResultHandler(writer.Handle))
+	}
+
+	// forcedExit is set when a second signal cut the shutdown short.
+	forcedExit := false
+	if *reportTo != "" {
+		name := *agentName
+		if name == "" {
+			name, _ = os.Hostname()
+		}
+		forwarder := agent.NewForwarder(*reportTo, name)
+		monitorOpts = append(monitorOpts, monitor.WithResultHandler(forwarder.Handle))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			forwarder.Run(ctx)
+		}()
+		// Flush what is left once the monitor has stopped, unless the exit
+		// was forced: then the process leaves without waiting for the flush.
+		defer func() {
+			cancel()
+			if !forcedExit {
+				<-done
+			}
+		}()
+	}
+
+	monitor := monitor.NewMonitor(http.DefaultClient, urls, monitorOpts...)
+	statsChan, unsubscribe := monitor.Subscribe()
+	defer unsubscribe()
 
 	display := application.NewCLIApplication(statsChan)
-	monitor := monitor.NewMonitor(http.DefaultClient, args, statsChan)
 
-	processor.New(monitor, display).Start()
+	if err := processor.New(monitor, display, processorOpts...).Start(); err != nil {
+		forcedExit = errors.Is(err, processor.ErrForcedExit)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func newHostLimiter(cfg config.HostLimits, concurrency int, rate float64) *limiter.HostLimiter {
+	toLimits := func(l config.HostLimit) limiter.Limits {
+		return limiter.Limits{MaxConcurrent: l.MaxConcurrent, RequestsPerSecond: l.RequestsPerSecond, Burst: l.Burst}
+	}
+
+	defaults := toLimits(cfg.Default)
+	if concurrency > 0 {
+		defaults.MaxConcurrent = concurrency
+	}
+	if rate > 0 {
+		defaults.RequestsPerSecond = rate
+	}
+	perHost := make(map[string]limiter.Limits)
+	for host, l := range cfg.Hosts {
+		perHost[host] = toLimits(l)
+	}
+	return limiter.New(defaults, perHost)
+}
+
+// isFlagSet reports whether name was given on the command line.
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 func removeDuplicates(slice []string) []string {
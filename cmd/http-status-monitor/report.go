@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/report"
+)
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func runReport(programName string, args []string) int {
+	flags := flag.NewFlagSet(programName+" report", flag.ContinueOnError)
+	logDir := flags.String("result-log", "", "directory of the result log written by --result-log")
+	since := flags.String("since", "24h", "start of the report: now, a duration before now or an RFC 3339 time")
+	until := flags.String("until", "now", "end of the report: now, a duration before now or an RFC 3339 time")
+	format := flags.String("format", "table", "output format: table or json")
+	var targets stringList
+	flags.Var(&targets, "target", "only report on this URL (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *logDir == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s report --result-log dir [--since 24h] [--until now] [--target url] [--format table|json]\n", programName)
+		return 2
+	}
+
+	now := time.Now()
+	from, err := report.ParseTime(*since, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--since: %v\n", err)
+		return 2
+	}
+	to, err := report.ParseTime(*until, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--until: %v\n", err)
+		return 2
+	}
+	if !to.After(from) {
+		fmt.Fprintf(os.Stderr, "--until must be after --since\n")
+		return 2
+	}
+
+	r, err := report.Build(*logDir, from, to, targets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "json":
+		if err := report.RenderJSON(os.Stdout, r); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+	case "table":
+		report.RenderTable(os.Stdout, r)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	return 0
+}
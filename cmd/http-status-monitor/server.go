@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/aggregator"
+)
+
+func runServer(programName string, args []string) int {
+	flags := flag.NewFlagSet(programName+" server", flag.ContinueOnError)
+	listen := flags.String("listen", ":8080", "address to accept agent results on")
+	staleAfter := flags.Duration("stale-after", aggregator.DefaultStaleAfter, "leave agents silent for longer than this out of the consensus")
+	renderInterval := flags.Duration("render-interval", time.Second, "redraw the table this often (0 disables the table)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	agg := aggregator.New(*staleAfter)
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	server := &http.Server{Handler: agg.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	var ticker <-chan time.Time
+	if *renderInterval > 0 {
+		t := time.NewTicker(*renderInterval)
+		defer t.Stop()
+		ticker = t.C
+	}
+
+	for {
+		select {
+		case <-ticker:
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Listening on %s\n", listener.Addr())
+			aggregator.RenderTable(os.Stdout, agg.View())
+		case err := <-serveErr:
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		case <-ctx.Done():
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdown); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return 1
+			}
+			aggregator.RenderTable(os.Stdout, agg.View())
+			return 0
+		}
+	}
+}
@@ -59,8 +59,9 @@ func TestMonitor_MultipleURLs(t *testing.T) {
 	}}
 	client := &http.Client{Transport: responder}
 
-	statsChan := make(chan map[string]*schema.URLStats, 10)
-	mon := monitor.NewMonitor(client, urls, statsChan)
+	mon := monitor.NewMonitor(client, urls)
+	statsChan, unsubscribe := mon.Subscribe()
+	defer unsubscribe()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Second)
 	defer cancel()
@@ -120,8 +121,9 @@ func TestMonitor_Timeout(t *testing.T) {
 	url := "https://timeout.com"
 	client := &http.Client{Transport: &timeoutRoundTripper{}}
 
-	statsChan := make(chan map[string]*schema.URLStats, 5)
-	mon := monitor.NewMonitor(client, []string{url}, statsChan)
+	mon := monitor.NewMonitor(client, []string{url})
+	statsChan, unsubscribe := mon.Subscribe()
+	defer unsubscribe()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -169,8 +171,9 @@ func TestMonitor_HTTPStatusCodes(t *testing.T) {
 	}}
 	client := &http.Client{Transport: responder}
 
-	statsChan := make(chan map[string]*schema.URLStats, 10)
-	mon := monitor.NewMonitor(client, urls, statsChan)
+	mon := monitor.NewMonitor(client, urls)
+	statsChan, unsubscribe := mon.Subscribe()
+	defer unsubscribe()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Second)
 	defer cancel()
@@ -214,8 +217,9 @@ func TestMonitor_PayloadSizeStats(t *testing.T) {
 	body := "1234567890"
 	client := &http.Client{Transport: &staticResponder{status: 200, body: body}}
 
-	statsChan := make(chan map[string]*schema.URLStats, 5)
-	mon := monitor.NewMonitor(client, []string{url}, statsChan)
+	mon := monitor.NewMonitor(client, []string{url})
+	statsChan, unsubscribe := mon.Subscribe()
+	defer unsubscribe()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Second)
 	defer cancel()
@@ -265,8 +269,9 @@ func TestMonitor_ManyParallelURLs(t *testing.T) {
 	}
 	client := &http.Client{Transport: &multiResponder{responders: responders}}
 
-	statsChan := make(chan map[string]*schema.URLStats, 20)
-	mon := monitor.NewMonitor(client, urls, statsChan)
+	mon := monitor.NewMonitor(client, urls)
+	statsChan, unsubscribe := mon.Subscribe()
+	defer unsubscribe()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Second)
 	defer cancel()
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/resultlog"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ResultsPath is where agents post their results on the server, one
+	// resultlog.Record JSON object per line.
+	ResultsPath = "/v1/results"
+	// NameHeader names the agent a batch of results comes from.
+	NameHeader = "X-Agent-Name"
+
+	DefaultFlushInterval = time.Second
+	DefaultMaxBuffer     = 10000
+)
+
+// Forwarder sends the results of a local monitor to a central server. Results
+// are buffered and posted in batches; a batch the server did not accept is
+// sent again with the next one. While the server is unreachable the buffer
+// keeps the newest MaxBuffer results and drops the oldest.
+type Forwarder struct {
+	endpoint  string
+	name      string
+	client    *http.Client
+	interval  time.Duration
+	maxBuffer int
+
+	mutex   sync.Mutex
+	buffer  []resultlog.Record
+	dropped int
+}
+
+type Option func(*Forwarder)
+
+func WithClient(client *http.Client) Option {
+	return func(f *Forwarder) {
+		f.client = client
+	}
+}
+
+func WithFlushInterval(interval time.Duration) Option {
+	return func(f *Forwarder) {
+		f.interval = interval
+	}
+}
+
+func WithMaxBuffer(size int) Option {
+	return func(f *Forwarder) {
+		f.maxBuffer = size
+	}
+}
+
+func NewForwarder(serverURL, name string, opts ...Option) *Forwarder {
+	f := &Forwarder{
+		endpoint:  strings.TrimSuffix(serverURL, "/") + ResultsPath,
+		name:      name,
+		client:    http.DefaultClient,
+		interval:  DefaultFlushInterval,
+		maxBuffer: DefaultMaxBuffer,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Handle queues a result for the server. It matches the monitor result
+// handler signature and never blocks on the network.
+func (f *Forwarder) Handle(result schema.RequestResult) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.buffer = append(f.buffer, resultlog.FromResult(result))
+	if over := len(f.buffer) - f.maxBuffer; over > 0 {
+		f.buffer = f.buffer[over:]
+		f.dropped += over
+	}
+}
+
+// Dropped returns how many results were discarded because the buffer was
+// full.
+func (f *Forwarder) Dropped() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.dropped
+}
+
+// Run flushes the buffer every flush interval until ctx is done, and once more
+// afterwards.
+func (f *Forwarder) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.Flush(ctx); err != nil {
+				log.WithError(err).Warn("failed to forward results")
+			}
+		case <-ctx.Done():
+			final, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := f.Flush(final); err != nil {
+				log.WithError(err).Warn("failed to forward final results")
+			}
+			return
+		}
+	}
+}
+
+// Flush posts the buffered results. On failure they stay buffered.
+func (f *Forwarder) Flush(ctx context.Context) error {
+	f.mutex.Lock()
+	batch := f.buffer
+	f.buffer = nil
+	f.mutex.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := f.post(ctx, batch); err != nil {
+		f.requeue(batch)
+		return err
+	}
+	return nil
+}
+
+func (f *Forwarder) post(ctx context.Context, batch []resultlog.Record) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, rec := range batch {
+		if err := encoder.Encode(rec); err != nil {
+			return fmt.Errorf("encode result: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set(NameHeader, f.name)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post results: %w", err)
+	}
+	resp.Body.Close() //nolint
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("post results: server answered %s", resp.Status)
+	}
+	return nil
+}
+
+// requeue puts a failed batch back in front of the results queued since.
+func (f *Forwarder) requeue(batch []resultlog.Record) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.buffer = append(batch, f.buffer...)
+	if over := len(f.buffer) - f.maxBuffer; over > 0 {
+		f.buffer = f.buffer[over:]
+		f.dropped += over
+	}
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dvdk01/http-status-monitor/internal/resultlog"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type collector struct {
+	mutex   sync.Mutex
+	fail    bool
+	agents  []string
+	records []resultlog.Record
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	c.agents = append(c.agents, r.Header.Get(NameHeader))
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var rec resultlog.Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err == nil {
+			c.records = append(c.records, rec)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestForwarder_Flush(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a batch of results sent to a healthy server
+	// Verifies that every result arrives once, tagged with the agent name
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	f := NewForwarder(server.URL, "eu-west")
+	f.Handle(schema.RequestResult{URL: "http://a.com", Success: true, Status: 200})
+	f.Handle(schema.RequestResult{URL: "http://b.com", Success: false})
+
+	require.NoError(t, f.Flush(context.Background()))
+	require.NoError(t, f.Flush(context.Background()))
+
+	assert.Equal(t, []string{"eu-west"}, c.agents)
+	require.Len(t, c.records, 2)
+	assert.Equal(t, "http://a.com", c.records[0].URL)
+	assert.False(t, c.records[1].Success)
+}
+
+func TestForwarder_FlushRetriesFailedBatch(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a server rejecting a batch
+	// Verifies that the results are kept in order and sent with the next flush
+	c := &collector{fail: true}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	f := NewForwarder(server.URL, "us-east")
+	f.Handle(schema.RequestResult{URL: "http://a.com"})
+	assert.Error(t, f.Flush(context.Background()))
+
+	f.Handle(schema.RequestResult{URL: "http://b.com"})
+	c.mutex.Lock()
+	c.fail = false
+	c.mutex.Unlock()
+	require.NoError(t, f.Flush(context.Background()))
+
+	require.Len(t, c.records, 2)
+	assert.Equal(t, "http://a.com", c.records[0].URL)
+	assert.Equal(t, "http://b.com", c.records[1].URL)
+}
+
+func TestForwarder_HandleDropsOldest(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a full buffer while the server is unreachable
+	// Verifies that the newest results are kept and drops are counted
+	f := NewForwarder("http://127.0.0.1:0", "ap-south", WithMaxBuffer(2))
+	for _, url := range []string{"http://1.com", "http://2.com", "http://3.com"} {
+		f.Handle(schema.RequestResult{URL: url})
+	}
+
+	assert.Equal(t, 1, f.Dropped())
+	assert.Equal(t, "http://2.com", f.buffer[0].URL)
+	assert.Equal(t, "http://3.com", f.buffer[1].URL)
+}
@@ -0,0 +1,128 @@
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// DefaultStaleAfter is how long an agent may stay silent about a target
+// before it no longer counts towards the consensus.
+const DefaultStaleAfter = time.Minute
+
+// Aggregator merges the results streamed by agents into stats per target and
+// agent.
+type Aggregator struct {
+	staleAfter time.Duration
+	now        func() time.Time
+
+	mutex   sync.Mutex
+	targets map[string]map[string]*agentStats
+}
+
+type agentStats struct {
+	stats    *schema.URLStats
+	lastSeen time.Time
+}
+
+func New(staleAfter time.Duration) *Aggregator {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	return &Aggregator{
+		staleAfter: staleAfter,
+		now:        time.Now,
+		targets:    make(map[string]map[string]*agentStats),
+	}
+}
+
+func (a *Aggregator) Observe(agent string, result schema.RequestResult) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	agents, ok := a.targets[result.URL]
+	if !ok {
+		agents = make(map[string]*agentStats)
+		a.targets[result.URL] = agents
+	}
+	s, ok := agents[agent]
+	if !ok {
+		s = &agentStats{stats: schema.NewURLStats(result.URL)}
+		agents[agent] = s
+	}
+	s.stats.Add(result)
+	s.lastSeen = a.now()
+}
+
+type AgentView struct {
+	Agent    string           `json:"agent"`
+	LastSeen time.Time        `json:"last_seen"`
+	Stale    bool             `json:"stale"`
+	Stats    *schema.URLStats `json:"stats"`
+}
+
+// TargetView is the combined view of one target. Reporting counts the agents
+// that are not stale and Down lists those among them whose last probe failed.
+type TargetView struct {
+	URL       string      `json:"url"`
+	Reporting int         `json:"reporting"`
+	Down      []string    `json:"down"`
+	Consensus string      `json:"consensus"`
+	Agents    []AgentView `json:"agents"`
+}
+
+// View returns every target sorted by URL, with its agents sorted by name.
+func (a *Aggregator) View() []TargetView {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := a.now()
+	views := make([]TargetView, 0, len(a.targets))
+	for url, agents := range a.targets {
+		view := TargetView{URL: url, Down: []string{}}
+		for name, s := range agents {
+			stats := *s.stats
+			stats.StatusCodes = make(map[int]int, len(s.stats.StatusCodes))
+			for code, count := range s.stats.StatusCodes {
+				stats.StatusCodes[code] = count
+			}
+			if s.stats.Codes != nil {
+				stats.Codes = make(map[string]int, len(s.stats.Codes))
+				for code, count := range s.stats.Codes {
+					stats.Codes[code] = count
+				}
+			}
+			agent := AgentView{Agent: name, LastSeen: s.lastSeen, Stale: now.Sub(s.lastSeen) > a.staleAfter, Stats: &stats}
+			view.Agents = append(view.Agents, agent)
+
+			if agent.Stale {
+				continue
+			}
+			view.Reporting++
+			if stats.IsDown() {
+				view.Down = append(view.Down, name)
+			}
+		}
+		sort.Slice(view.Agents, func(i, j int) bool { return view.Agents[i].Agent < view.Agents[j].Agent })
+		sort.Strings(view.Down)
+		view.Consensus = consensus(view.Reporting, len(view.Down))
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].URL < views[j].URL })
+	return views
+}
+
+func consensus(reporting, down int) string {
+	switch {
+	case reporting == 0:
+		return "no recent data"
+	case down == 0:
+		return fmt.Sprintf("up from all %d locations", reporting)
+	case down == reporting:
+		return fmt.Sprintf("down from all %d locations", reporting)
+	}
+	return fmt.Sprintf("down from %d of %d locations", down, reporting)
+}
@@ -0,0 +1,193 @@
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/agent"
+	"github.com/dvdk01/http-status-monitor/internal/monitor"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator_View(t *testing.T) {
+	t.Parallel()
+
+	// Test case for three agents disagreeing about a target
+	// Verifies that stats are kept per agent and the consensus counts failing agents
+	a := New(time.Minute)
+	a.Observe("eu", schema.RequestResult{URL: "http://a.com", Success: true, Status: 200})
+	a.Observe("us", schema.RequestResult{URL: "http://a.com", Success: false})
+	a.Observe("ap", schema.RequestResult{URL: "http://a.com", Success: false})
+	a.Observe("ap", schema.RequestResult{URL: "http://a.com", Success: false})
+
+	views := a.View()
+	require.Len(t, views, 1)
+	view := views[0]
+	assert.Equal(t, 3, view.Reporting)
+	assert.Equal(t, []string{"ap", "us"}, view.Down)
+	assert.Equal(t, "down from 2 of 3 locations", view.Consensus)
+
+	require.Len(t, view.Agents, 3)
+	assert.Equal(t, "ap", view.Agents[0].Agent)
+	assert.Equal(t, 2, view.Agents[0].Stats.TotalRequests)
+	assert.Equal(t, 2, view.Agents[0].Stats.ConsecutiveFailures)
+}
+
+func TestAggregator_ViewStaleAgents(t *testing.T) {
+	t.Parallel()
+
+	// Test case for an agent that stopped reporting
+	// Verifies that it is shown as stale and left out of the consensus
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := New(time.Minute)
+	a.now = func() time.Time { return now }
+	a.Observe("eu", schema.RequestResult{URL: "http://a.com", Success: false})
+	now = now.Add(2 * time.Minute)
+	a.Observe("us", schema.RequestResult{URL: "http://a.com", Success: true})
+
+	view := a.View()[0]
+	assert.True(t, view.Agents[0].Stale)
+	assert.Equal(t, 1, view.Reporting)
+	assert.Equal(t, "up from all 1 locations", view.Consensus)
+}
+
+func TestConsensus(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "no recent data", consensus(0, 0))
+	assert.Equal(t, "up from all 3 locations", consensus(3, 0))
+	assert.Equal(t, "down from 1 of 3 locations", consensus(3, 1))
+	assert.Equal(t, "down from all 2 locations", consensus(2, 2))
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	// Test case for the HTTP endpoints
+	// Verifies that posted results show up in the JSON and table views and bad
+	// posts are rejected without counting any of their records
+	a := New(time.Minute)
+	server := httptest.NewServer(a.Handler())
+	defer server.Close()
+
+	body := `{"time":"2026-01-01T12:00:00Z","url":"http://a.com","duration":1000000,"payload_size":2,"status":200,"success":true}` + "\n"
+	req, err := http.NewRequest(http.MethodPost, server.URL+agent.ResultsPath, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set(agent.NameHeader, "eu")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close() //nolint
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+agent.ResultsPath, "application/x-ndjson", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close() //nolint
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodPost, server.URL+agent.ResultsPath, strings.NewReader(body+"{not json\n"+body))
+	require.NoError(t, err)
+	req.Header.Set(agent.NameHeader, "eu")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close() //nolint
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/v1/stats")
+	require.NoError(t, err)
+	var views []TargetView
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&views))
+	resp.Body.Close() //nolint
+	require.Len(t, views, 1)
+	assert.Equal(t, "up from all 1 locations", views[0].Consensus)
+	assert.Equal(t, 1, views[0].Agents[0].Stats.SuccessCount)
+
+	// Test case for a dns result retried before it succeeded
+	// Verifies that attempts, check types and codes reach the agent's stats
+	retried := `{"time":"2026-01-01T12:00:00Z","url":"dns:///a.com","duration":1000000,"success":true,"check_type":"dns","attempts":2,"code":"NOERROR"}` + "\n"
+	req, err = http.NewRequest(http.MethodPost, server.URL+agent.ResultsPath, strings.NewReader(retried))
+	require.NoError(t, err)
+	req.Header.Set(agent.NameHeader, "eu")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close() //nolint
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	dns := a.View()[0].Agents[0].Stats
+	assert.Equal(t, "dns:///a.com", dns.URL)
+	assert.Zero(t, dns.FirstAttemptSuccessCount)
+	assert.Equal(t, 2, dns.TotalAttempts)
+	assert.Equal(t, map[string]int{"NOERROR": 1}, dns.Codes)
+
+	resp, err = http.Get(server.URL + "/")
+	require.NoError(t, err)
+	var table bytes.Buffer
+	table.ReadFrom(resp.Body) //nolint
+	resp.Body.Close()         //nolint
+	assert.Contains(t, table.String(), "http://a.com")
+	assert.Contains(t, table.String(), "eu")
+	assert.Contains(t, table.String(), "NOERROR:1")
+}
+
+// zoneTransport answers every probe with the status the target has as seen
+// from one network zone.
+type zoneTransport struct {
+	status int
+}
+
+func (z zoneTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: z.status,
+		Body:       http.NoBody,
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestAgentsOnLoopback(t *testing.T) {
+	t.Parallel()
+
+	// Test case for three agents in different zones streaming to one server
+	// Verifies that the server reaches a consensus from the results of every agent
+	a := New(time.Minute)
+	server := httptest.NewServer(a.Handler())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	target := "http://shop.example.com"
+	zones := map[string]int{"eu-west": 200, "us-east": 503, "ap-south": 200}
+	for name, status := range zones {
+		forwarder := agent.NewForwarder(server.URL, name, agent.WithFlushInterval(10*time.Millisecond))
+		mon := monitor.NewMonitor(&http.Client{Transport: zoneTransport{status: status}}, []string{target},
+			monitor.WithInterval(10*time.Millisecond),
+			monitor.WithResultHandler(forwarder.Handle),
+		)
+		go forwarder.Run(ctx)
+		go mon.Start(ctx) //nolint:errcheck
+	}
+
+	var view TargetView
+	require.Eventually(t, func() bool {
+		views := a.View()
+		if len(views) != 1 {
+			return false
+		}
+		view = views[0]
+		return view.Reporting == len(zones)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "down from 1 of 3 locations", view.Consensus)
+	assert.Equal(t, []string{"us-east"}, view.Down)
+	for _, agentView := range view.Agents {
+		assert.Positive(t, agentView.Stats.TotalRequests, fmt.Sprintf("agent %s", agentView.Agent))
+	}
+}
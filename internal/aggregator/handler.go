@@ -0,0 +1,67 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/dvdk01/http-status-monitor/internal/agent"
+	"github.com/dvdk01/http-status-monitor/internal/resultlog"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxBatchSize bounds the body of a single results post.
+const maxBatchSize = 16 << 20
+
+// Handler serves the agent results endpoint, the combined stats as JSON on
+// /v1/stats and as a plain text table on /.
+func (a *Aggregator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+agent.ResultsPath, a.handleResults)
+	mux.HandleFunc("GET /v1/stats", a.handleStats)
+	mux.HandleFunc("GET /{$}", a.handleTable)
+	return mux
+}
+
+func (a *Aggregator) handleResults(w http.ResponseWriter, r *http.Request) {
+	name := r.Header.Get(agent.NameHeader)
+	if name == "" {
+		http.Error(w, "missing "+agent.NameHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	// The whole batch is decoded before any of it is observed: agents resend
+	// rejected batches, which must not count twice.
+	var batch []resultlog.Record
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchSize))
+	for {
+		var rec resultlog.Record
+		err := decoder.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.WithError(err).WithField("agent", name).Warn("rejected results batch")
+			http.Error(w, "malformed results: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		batch = append(batch, rec)
+	}
+	for _, rec := range batch {
+		a.Observe(name, rec.Result())
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Aggregator) handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := RenderJSON(w, a.View()); err != nil {
+		log.WithError(err).Error("failed to write stats")
+	}
+}
+
+func (a *Aggregator) handleTable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	RenderTable(w, a.View())
+}
@@ -0,0 +1,84 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func RenderJSON(w io.Writer, views []TargetView) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(views)
+}
+
+// RenderTable prints one row per target and agent. The target and its
+// consensus are shown on the first row of each target only.
+func RenderTable(w io.Writer, views []TargetView) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"URL", "Consensus", "Agent", "Status", "1st Try", "Codes", "Last Probe", "Avg Duration", "Last Seen"})
+
+	now := time.Now()
+	for _, view := range views {
+		for i, agent := range view.Agents {
+			url, consensus := "", ""
+			if i == 0 {
+				url, consensus = view.URL, view.Consensus
+			}
+
+			stats := agent.Stats
+			last := "up"
+			if stats.IsDown() {
+				last = fmt.Sprintf("down (%d failures)", stats.ConsecutiveFailures)
+			}
+			if agent.Stale {
+				last = "stale"
+			}
+
+			t.AppendRow(table.Row{
+				url,
+				consensus,
+				agent.Agent,
+				fmt.Sprintf("%d/%d %d%%", stats.SuccessCount, stats.TotalRequests, stats.SuccessPercentage()),
+				fmt.Sprintf("%d%%", stats.FirstAttemptSuccessPercentage()),
+				formatCodes(stats),
+				last,
+				stats.AvgDuration().Round(time.Millisecond),
+				fmt.Sprintf("%s ago", now.Sub(agent.LastSeen).Round(time.Second)),
+			})
+		}
+		t.AppendSeparator()
+	}
+	t.Render()
+}
+
+// formatCodes lists the http status codes and the codes of other checks seen
+// by an agent, in order.
+func formatCodes(stats *schema.URLStats) string {
+	var parts []string
+	statuses := make([]int, 0, len(stats.StatusCodes))
+	for status := range stats.StatusCodes {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		parts = append(parts, fmt.Sprintf("%d:%d", status, stats.StatusCodes[status]))
+	}
+	codes := make([]string, 0, len(stats.Codes))
+	for code := range stats.Codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%s:%d", code, stats.Codes[code]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,227 @@
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/maintenance"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+type State string
+
+const (
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+	// StateChanged reports a change that is not an outage, such as a dns
+	// target answering with different records.
+	StateChanged State = "changed"
+)
+
+type Alert struct {
+	Rule     string
+	URL      string
+	State    State
+	Repeat   bool
+	Failures int
+	Error    error
+	Time     time.Time
+	// Details describes what changed for StateChanged alerts.
+	Details string
+}
+
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+type logNotifier struct{}
+
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+func (logNotifier) Notify(a Alert) error {
+	entry := log.WithFields(log.Fields{
+		"rule":     a.Rule,
+		"url":      a.URL,
+		"failures": a.Failures,
+		"repeat":   a.Repeat,
+	})
+	if a.Error != nil {
+		entry = entry.WithError(a.Error)
+	}
+	switch a.State {
+	case StateFiring:
+		entry.Warn("target is down")
+	case StateChanged:
+		entry.WithField("details", a.Details).Warn("target changed")
+	default:
+		entry.Info("target recovered")
+	}
+	return nil
+}
+
+type targetState struct {
+	failures     int
+	firing       bool
+	lastNotified time.Time
+}
+
+type rule struct {
+	cfg    config.AlertRule
+	states map[string]*targetState
+	sent   []time.Time
+}
+
+// allow applies the rule's rate limit and records the send when permitted.
+func (r *rule) allow(now time.Time) bool {
+	if r.cfg.RateLimit.Count == 0 {
+		return true
+	}
+	cutoff := now.Add(-time.Duration(r.cfg.RateLimit.Per))
+	kept := r.sent[:0]
+	for _, t := range r.sent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	r.sent = kept
+	if len(r.sent) >= r.cfg.RateLimit.Count {
+		return false
+	}
+	r.sent = append(r.sent, now)
+	return true
+}
+
+// Manager turns probe results into notifications. Alerts for targets inside a
+// maintenance window are evaluated but not sent, and failures caused by a down
+// upstream are ignored so that only the upstream's incident is reported.
+type Manager struct {
+	mutex       sync.Mutex
+	rules       []*rule
+	targets     map[string]config.Target
+	maintenance *maintenance.Schedule
+	notifier    Notifier
+	now         func() time.Time
+}
+
+func NewManager(rules []config.AlertRule, targets []config.Target, schedule *maintenance.Schedule, notifier Notifier) *Manager {
+	m := &Manager{
+		targets:     make(map[string]config.Target),
+		maintenance: schedule,
+		notifier:    notifier,
+		now:         time.Now,
+	}
+	for _, t := range targets {
+		m.targets[t.URL] = t
+	}
+	for _, r := range rules {
+		if r.FailureThreshold <= 0 {
+			r.FailureThreshold = 1
+		}
+		m.rules = append(m.rules, &rule{cfg: r, states: make(map[string]*targetState)})
+	}
+	return m
+}
+
+func (m *Manager) Observe(result schema.RequestResult) {
+	now := m.now()
+	for _, a := range m.evaluate(result, now) {
+		if err := m.notifier.Notify(a); err != nil {
+			log.WithError(err).WithField("url", a.URL).Error("failed to send alert")
+		}
+	}
+}
+
+// evaluate returns the alerts to send for a result. A target's state only
+// changes with an alert that is sent, so an outage suppressed by a
+// maintenance window or the rate limit still fires once it may be sent, and
+// a recovery is only reported after its outage was.
+func (m *Manager) evaluate(result schema.RequestResult, now time.Time) []Alert {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !result.Success && result.UpstreamDown != "" {
+		return nil
+	}
+
+	target, ok := m.targets[result.URL]
+	if !ok {
+		target = config.Target{URL: result.URL}
+	}
+	window, inMaintenance := m.maintenance.Active(result.URL, now)
+
+	// send reports whether an alert of r may be sent now.
+	send := func(r *rule, a Alert) bool {
+		if inMaintenance {
+			log.WithField("url", a.URL).WithField("window", window).Debug("alert suppressed by maintenance window")
+			return false
+		}
+		if !r.allow(now) {
+			log.WithField("url", a.URL).WithField("rule", a.Rule).Debug("alert dropped by rate limit")
+			return false
+		}
+		return true
+	}
+
+	var alerts []Alert
+	for _, r := range m.rules {
+		if !config.Matches(target, r.cfg.Targets, r.cfg.Tags) {
+			continue
+		}
+		st, ok := r.states[result.URL]
+		if !ok {
+			st = &targetState{}
+			r.states[result.URL] = st
+		}
+
+		if dns := result.DNS; dns != nil && dns.Changed {
+			a := Alert{
+				Rule:    r.cfg.Name,
+				URL:     result.URL,
+				State:   StateChanged,
+				Time:    now,
+				Details: fmt.Sprintf("dns answer changed from %v to %v", dns.Previous, dns.Records),
+			}
+			if send(r, a) {
+				alerts = append(alerts, a)
+			}
+		}
+
+		a := Alert{Rule: r.cfg.Name, URL: result.URL, Error: result.Error, Time: now}
+		if result.Success {
+			st.failures = 0
+			if st.firing {
+				a.State = StateResolved
+				if send(r, a) {
+					st.firing = false
+					st.lastNotified = now
+					alerts = append(alerts, a)
+				}
+			}
+			continue
+		}
+
+		st.failures++
+		a.State = StateFiring
+		a.Failures = st.failures
+		switch {
+		case !st.firing && st.failures >= r.cfg.FailureThreshold:
+			// A new outage, or one not sent yet.
+		case st.firing && r.cfg.RepeatInterval > 0 && now.Sub(st.lastNotified) >= time.Duration(r.cfg.RepeatInterval):
+			a.Repeat = true
+		default:
+			continue
+		}
+		if !send(r, a) {
+			continue
+		}
+		st.firing = true
+		st.lastNotified = now
+		alerts = append(alerts, a)
+	}
+	return alerts
+}
@@ -0,0 +1,220 @@
+package alert
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/maintenance"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	alerts []Alert
+}
+
+func (r *recordingNotifier) Notify(a Alert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestManager(t *testing.T, rules []config.AlertRule, windows []config.MaintenanceWindow) (*Manager, *recordingNotifier, *clock) {
+	t.Helper()
+
+	targets := []config.Target{{URL: "https://a.com", Tags: []string{"api"}}, {URL: "https://b.com"}}
+	schedule, err := maintenance.New(windows, targets)
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	c := &clock{now: time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)}
+	m := NewManager(rules, targets, schedule, notifier)
+	m.now = c.Now
+	return m, notifier, c
+}
+
+var (
+	down = schema.RequestResult{URL: "https://a.com", Success: false, Error: errors.New("boom")}
+	up   = schema.RequestResult{URL: "https://a.com", Success: true, Status: 200}
+)
+
+func TestManager_FiresAndResolves(t *testing.T) {
+	// Test case for a simple outage and recovery
+	// Verifies that one firing alert is sent after the threshold and one resolved alert on recovery
+	m, n, _ := newTestManager(t, []config.AlertRule{{Name: "down", FailureThreshold: 2}}, nil)
+
+	m.Observe(down)
+	assert.Empty(t, n.alerts)
+
+	m.Observe(down)
+	m.Observe(down)
+	require.Len(t, n.alerts, 1)
+	assert.Equal(t, StateFiring, n.alerts[0].State)
+	assert.Equal(t, 2, n.alerts[0].Failures)
+
+	m.Observe(up)
+	require.Len(t, n.alerts, 2)
+	assert.Equal(t, StateResolved, n.alerts[1].State)
+}
+
+func TestManager_RepeatInterval(t *testing.T) {
+	// Test case for a long outage with a repeat interval
+	// Verifies that reminders are sent only once per repeat interval
+	m, n, c := newTestManager(t, []config.AlertRule{{Name: "down", RepeatInterval: config.Duration(10 * time.Minute)}}, nil)
+
+	for i := 0; i < 30; i++ {
+		m.Observe(down)
+		c.Advance(time.Minute)
+	}
+
+	require.Len(t, n.alerts, 3)
+	assert.False(t, n.alerts[0].Repeat)
+	assert.True(t, n.alerts[1].Repeat)
+	assert.True(t, n.alerts[2].Repeat)
+}
+
+func TestManager_RateLimit(t *testing.T) {
+	// Test case for a flapping target under a rate limited rule
+	// Verifies that no more than the configured number of alerts are sent per
+	// period, and that a dropped recovery is reported once the limit allows
+	m, n, c := newTestManager(t, []config.AlertRule{{
+		Name:      "down",
+		RateLimit: config.RateLimit{Count: 3, Per: config.Duration(time.Hour)},
+	}}, nil)
+
+	for i := 0; i < 10; i++ {
+		m.Observe(down)
+		m.Observe(up)
+		c.Advance(time.Minute)
+	}
+	assert.Len(t, n.alerts, 3)
+
+	c.Advance(time.Hour)
+	m.Observe(up)
+	require.Len(t, n.alerts, 4)
+	assert.Equal(t, StateResolved, n.alerts[3].State)
+
+	m.Observe(down)
+	assert.Len(t, n.alerts, 5)
+}
+
+func TestManager_MaintenanceSuppresses(t *testing.T) {
+	// Test case for an outage during a maintenance window
+	// Verifies that alerts are suppressed inside the window and resume after it
+	m, n, c := newTestManager(t, []config.AlertRule{{Name: "down"}}, []config.MaintenanceWindow{{
+		Name:  "deploy",
+		Tags:  []string{"api"},
+		Start: time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+	}})
+
+	m.Observe(down)
+	m.Observe(up)
+	assert.Empty(t, n.alerts)
+
+	c.Advance(time.Hour)
+	m.Observe(down)
+	require.Len(t, n.alerts, 1)
+	assert.Equal(t, StateFiring, n.alerts[0].State)
+}
+
+func TestManager_DownThroughMaintenance(t *testing.T) {
+	// Test case for an outage starting inside a maintenance window and
+	// lasting past its end
+	// Verifies that the outage pages once the window is over and its recovery is reported
+	m, n, c := newTestManager(t, []config.AlertRule{{Name: "down", FailureThreshold: 2}}, []config.MaintenanceWindow{{
+		Name:  "deploy",
+		Tags:  []string{"api"},
+		Start: time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+	}})
+
+	m.Observe(down)
+	m.Observe(down)
+	m.Observe(down)
+	assert.Empty(t, n.alerts)
+
+	c.Advance(time.Hour)
+	m.Observe(down)
+	require.Len(t, n.alerts, 1)
+	assert.Equal(t, StateFiring, n.alerts[0].State)
+	assert.Equal(t, 4, n.alerts[0].Failures)
+
+	m.Observe(up)
+	require.Len(t, n.alerts, 2)
+	assert.Equal(t, StateResolved, n.alerts[1].State)
+}
+
+func TestManager_NoResolvedWithoutFiring(t *testing.T) {
+	// Test case for a target recovering after an outage that was never reported
+	// Verifies that no resolved alert is sent without a firing one before it
+	m, n, c := newTestManager(t, []config.AlertRule{{Name: "down"}}, []config.MaintenanceWindow{{
+		Name:  "deploy",
+		Tags:  []string{"api"},
+		Start: time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+	}})
+
+	m.Observe(down)
+	c.Advance(time.Hour)
+	m.Observe(up)
+	m.Observe(up)
+	assert.Empty(t, n.alerts)
+}
+
+func TestManager_RuleSelectors(t *testing.T) {
+	// Test case for rules scoped to specific targets or tags
+	// Verifies that a rule only evaluates the targets it selects
+	m, n, _ := newTestManager(t, []config.AlertRule{{Name: "b-only", Targets: []string{"https://b.com"}}}, nil)
+
+	m.Observe(down)
+	assert.Empty(t, n.alerts)
+
+	m.Observe(schema.RequestResult{URL: "https://b.com", Success: false})
+	require.Len(t, n.alerts, 1)
+	assert.Equal(t, "https://b.com", n.alerts[0].URL)
+}
+
+func TestManager_UpstreamDownSuppresses(t *testing.T) {
+	// Test case for a dependent target failing while its upstream is down
+	// Verifies that its failures raise no alert until the upstream recovers
+	m, n, _ := newTestManager(t, []config.AlertRule{{Name: "down"}}, nil)
+
+	m.Observe(schema.RequestResult{URL: "https://a.com", Success: false, UpstreamDown: "https://gw.com"})
+	m.Observe(schema.RequestResult{URL: "https://a.com", Success: false, UpstreamDown: "https://gw.com"})
+	assert.Empty(t, n.alerts)
+
+	m.Observe(down)
+	require.Len(t, n.alerts, 1)
+	assert.Equal(t, 1, n.alerts[0].Failures)
+}
+
+func TestManager_DNSAnswerChanged(t *testing.T) {
+	// Test case for a dns target answering with new records
+	// Verifies that the change is notified once even though the target stays up
+	m, n, _ := newTestManager(t, []config.AlertRule{{Name: "down"}}, nil)
+
+	answer := &schema.DNSAnswer{RCode: "NOERROR", Records: []string{"192.0.2.1"}}
+	m.Observe(schema.RequestResult{URL: "https://a.com", Success: true, DNS: answer})
+	assert.Empty(t, n.alerts)
+
+	changed := &schema.DNSAnswer{RCode: "NOERROR", Records: []string{"203.0.113.9"}, Changed: true, Previous: answer.Records}
+	m.Observe(schema.RequestResult{URL: "https://a.com", Success: true, DNS: changed})
+	require.Len(t, n.alerts, 1)
+	assert.Equal(t, StateChanged, n.alerts[0].State)
+	assert.Contains(t, n.alerts[0].Details, "203.0.113.9")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/dvdk01/http-status-monitor/internal/schema"
@@ -14,10 +15,10 @@ import (
 )
 
 type cliApplication struct {
-	statsChan chan map[string]*schema.URLStats
+	statsChan <-chan map[string]*schema.URLStats
 }
 
-func NewCLIApplication(statsChan chan map[string]*schema.URLStats) *cliApplication {
+func NewCLIApplication(statsChan <-chan map[string]*schema.URLStats) *cliApplication {
 	return &cliApplication{statsChan: statsChan}
 }
 
@@ -42,7 +43,10 @@ func (ca *cliApplication) Start(ctx context.Context) error {
 			select {
 			case <-ctx.Done():
 				return
-			case stats := <-ca.statsChan:
+			case stats, ok := <-ca.statsChan:
+				if !ok {
+					return
+				}
 				ca.Render(stats)
 
 			}
@@ -79,16 +83,68 @@ func colorizeStatusCode(code int, txt string) string {
 	}
 }
 
+func roundTrip(stat *schema.URLStats) string {
+	if stat.RoundTrips == 0 {
+		return ""
+	}
+	return fmt.Sprint(stat.AvgRoundTrip().Round(time.Millisecond))
+}
+
+// firstEvent is the average time to the first event of sse checks, with the
+// longest silence seen on the stream.
+func firstEvent(stat *schema.URLStats) string {
+	if stat.FirstEvents == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v (max gap %v)", stat.AvgFirstEvent().Round(time.Millisecond), stat.MaxStreamGap.Round(time.Millisecond))
+}
+
+func certExpiry(cert *schema.CertInfo) string {
+	if cert == nil {
+		return ""
+	}
+	days := int(cert.ExpiresIn(time.Now()).Hours() / 24)
+	txt := fmt.Sprintf("in %dd", days)
+	if cert.State == schema.CertExpired {
+		txt = fmt.Sprintf("%dd ago", -days)
+	}
+	if !cert.HostnameMatch {
+		txt += " host mismatch"
+	}
+	if !cert.ChainValid {
+		txt += " untrusted"
+	}
+	switch {
+	case cert.State == schema.CertCritical || cert.State == schema.CertExpired || !cert.HostnameMatch || !cert.ChainValid:
+		return text.FgRed.Sprint(txt)
+	case cert.State == schema.CertWarning:
+		return text.FgYellow.Sprint(txt)
+	default:
+		return text.FgGreen.Sprint(txt)
+	}
+}
+
+// colorizeCode colors the response codes of checks other than http, which
+// are green when they mean success.
+func colorizeCode(code, txt string) string {
+	switch code {
+	case "NOERROR", "SERVING":
+		return text.FgGreen.Sprint(txt)
+	default:
+		return text.FgRed.Sprint(txt)
+	}
+}
+
 func dumpTable(stats map[string]*schema.URLStats) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
 	t.SetStyle(table.StyleLight)
 
 	t.AppendHeader(table.Row{
-		"URL", "Status",
+		"URL", "Status", "1st Try",
 		"Min Duration", "Max Duration", "Avg Duration",
 		"Min Payload", "Max Payload", "Avg Payload",
-		"Status Codes",
+		"Status Codes", "Answers", "Cert Expires", "New Conn", "Reused Conn", "Round Trip", "First Event", "Interval", "Sched Lag", "Host Wait",
 	})
 
 	// Sort URLs alphabetically
@@ -103,19 +159,40 @@ func dumpTable(stats map[string]*schema.URLStats) {
 		successRate := stat.SuccessPercentage()
 		status := fmt.Sprintf("%d/%d %d%%", stat.SuccessCount, stat.TotalRequests, successRate)
 		status = colorizeStatus(successRate, status)
+		if stat.InMaintenance {
+			status += " " + text.FgBlue.Sprint("in maintenance")
+		}
+		if stat.UpstreamDown != "" {
+			status += " " + text.FgMagenta.Sprint("upstream down")
+		}
+		if stat.Degraded {
+			status += " " + text.FgYellow.Sprint("degraded")
+		}
 
 		statusCodes := ""
 		for code, count := range stat.StatusCodes {
 			codeText := fmt.Sprintf("%d:%d", code, count)
 			statusCodes += colorizeStatusCode(code, codeText) + " "
 		}
+		for code, count := range stat.Codes {
+			statusCodes += colorizeCode(code, fmt.Sprintf("%s:%d", code, count)) + " "
+		}
 		if statusCodes == "" {
 			statusCodes = "NO STATUS CODE"
 		}
 
+		answers := ""
+		if stat.LastRecords != nil {
+			answers = fmt.Sprintf("%d", stat.LastAnswerCount)
+			if stat.AnswerChanges > 0 {
+				answers += " " + text.FgYellow.Sprintf("(%d changes)", stat.AnswerChanges)
+			}
+		}
+
 		t.AppendRow(table.Row{
 			url,
 			status,
+			colorizeStatus(stat.FirstAttemptSuccessPercentage(), fmt.Sprintf("%d%%", stat.FirstAttemptSuccessPercentage())),
 			stat.MinDuration.Round(time.Millisecond),
 			stat.MaxDuration.Round(time.Millisecond),
 			stat.AvgDuration().Round(time.Millisecond),
@@ -123,8 +200,50 @@ func dumpTable(stats map[string]*schema.URLStats) {
 			fmt.Sprintf("%dB", stat.MaxPayload),
 			fmt.Sprintf("%dB", stat.AvgPayload()),
 			statusCodes,
+			answers,
+			certExpiry(stat.Cert),
+			fmt.Sprintf("%d × %v (setup %v)", stat.NewConnections, stat.AvgNewConnDuration().Round(time.Millisecond), stat.AvgNewConnSetup().Round(time.Millisecond)),
+			fmt.Sprintf("%d × %v", stat.ReusedConnections, stat.AvgReusedDuration().Round(time.Millisecond)),
+			roundTrip(stat),
+			firstEvent(stat),
+			stat.EffectiveInterval,
+			stat.AvgSchedulingLag().Round(time.Millisecond),
+			stat.AvgLimiterDelay().Round(time.Millisecond),
 		})
 	}
 
 	t.Render()
+	dumpTLSAudits(stats, urls)
+}
+
+// dumpTLSAudits lists what tls-audit checks found, below the main table.
+func dumpTLSAudits(stats map[string]*schema.URLStats, urls []string) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetStyle(table.StyleLight)
+	t.SetTitle("TLS audit")
+	t.AppendHeader(table.Row{"URL", "Versions", "Cipher Groups", "OCSP Stapling", "HSTS", "Findings"})
+
+	for _, url := range urls {
+		audit := stats[url].TLSAudit
+		if audit == nil {
+			continue
+		}
+		findings := text.FgGreen.Sprint("none")
+		if len(audit.Findings) > 0 {
+			findings = text.FgYellow.Sprint(strings.Join(audit.Findings, "; "))
+		}
+		t.AppendRow(table.Row{
+			url,
+			strings.Join(audit.Versions, ", "),
+			strings.Join(audit.CipherGroups, ", "),
+			audit.OCSPStapled,
+			audit.HSTS,
+			findings,
+		})
+	}
+
+	if t.Length() > 0 {
+		t.Render()
+	}
 }
@@ -0,0 +1,435 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Targets     []Target            `json:"targets"`
+	Maintenance []MaintenanceWindow `json:"maintenance"`
+	Alerts      []AlertRule         `json:"alerts"`
+	HostLimits  HostLimits          `json:"host_limits"`
+}
+
+type Target struct {
+	URL       string      `json:"url"`
+	Tags      []string    `json:"tags"`
+	DependsOn []string    `json:"depends_on"`
+	Retry     RetryPolicy `json:"retry"`
+	// Interval overrides the default time between probes of this target.
+	Interval Duration         `json:"interval"`
+	Adaptive AdaptiveInterval `json:"adaptive"`
+	// MaxBodySize aborts reading a response body after this many bytes and
+	// fails the probe. Zero reads bodies of any size.
+	MaxBodySize int64            `json:"max_body_size"`
+	Content     ContentAssertion `json:"content"`
+	// Connection is reuse (the default), fresh or alternate.
+	Connection string `json:"connection"`
+	// Check overrides the check type implied by the URL scheme.
+	Check string    `json:"check"`
+	TCP   TCPCheck  `json:"tcp"`
+	DNS   DNSCheck  `json:"dns"`
+	Cert  CertCheck `json:"cert"`
+	// WebSocket configures ws:// and wss:// targets.
+	WebSocket MessageCheck `json:"websocket"`
+	GRPC      GRPCCheck    `json:"grpc"`
+	GraphQL   GraphQLCheck `json:"graphql"`
+	SSE       SSECheck     `json:"sse"`
+}
+
+// SSECheck configures sse checks, which wait up to Deadline, the probe
+// timeout by default, for Events events (1 by default) whose data matches
+// the regular expression Match. A stream silent for longer than Heartbeat
+// fails the check.
+type SSECheck struct {
+	Events    int      `json:"events"`
+	Match     string   `json:"match"`
+	Deadline  Duration `json:"deadline"`
+	Heartbeat Duration `json:"heartbeat"`
+}
+
+// GraphQLCheck is the query POSTed by graphql checks. Expect maps dotted
+// paths into the response data, such as "user.roles.0", to the values they
+// must hold.
+type GraphQLCheck struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+	Expect    map[string]any `json:"expect"`
+}
+
+// GRPCCheck configures grpc:// targets. TLS makes the health check over TLS.
+type GRPCCheck struct {
+	TLS bool `json:"tls"`
+}
+
+// MessageCheck sends Send after connecting and expects the reply to match
+// the regular expression Expect. Without Send the check only connects.
+type MessageCheck struct {
+	Send   string `json:"send"`
+	Expect string `json:"expect"`
+}
+
+// CertCheck sets how many days before expiry the certificate of an https or
+// tls:// target degrades its probes (WarningDays, 21 by default) and fails
+// them (CriticalDays, 7 by default).
+type CertCheck struct {
+	WarningDays  int `json:"warning_days"`
+	CriticalDays int `json:"critical_days"`
+}
+
+// DNSCheck configures a dns:// target. Resolver (host:port) is used when the
+// URL names none, and every value in Expect must be among the answered
+// records of the queried type.
+type DNSCheck struct {
+	Resolver string   `json:"resolver"`
+	Expect   []string `json:"expect"`
+}
+
+// TCPCheck sends Send after connecting to a tcp:// target and expects the
+// response to match the regular expression Expect within its first
+// BannerSize bytes, 4 KiB by default. Without either the check only connects.
+type TCPCheck struct {
+	Send       string `json:"send"`
+	Expect     string `json:"expect"`
+	BannerSize int    `json:"banner_size"`
+}
+
+// ContentAssertion fails a probe whose response body does not contain
+// Contains or match the regular expression Matches. Only the first
+// PrefixSize bytes of the body are checked, 64 KiB by default.
+type ContentAssertion struct {
+	Contains   string `json:"contains"`
+	Matches    string `json:"matches"`
+	PrefixSize int    `json:"prefix_size"`
+}
+
+// AdaptiveInterval lets the probe interval move between MinInterval, used
+// while the target is failing, and MaxInterval, reached step by step after
+// StableAfter successful probes in a row. Zero bounds keep it fixed.
+type AdaptiveInterval struct {
+	MinInterval Duration `json:"min_interval"`
+	MaxInterval Duration `json:"max_interval"`
+	StableAfter int      `json:"stable_after"`
+}
+
+// RetryPolicy retries a failed probe before it is recorded. RetryOn lists
+// error categories (timeout, dns, connection, tls, other), status classes
+// such as 5xx or exact status codes; it defaults to timeout, dns and
+// connection errors.
+type RetryPolicy struct {
+	MaxAttempts    int      `json:"max_attempts"`
+	RetryOn        []string `json:"retry_on"`
+	InitialBackoff Duration `json:"initial_backoff"`
+	MaxBackoff     Duration `json:"max_backoff"`
+	Multiplier     float64  `json:"multiplier"`
+	Jitter         float64  `json:"jitter"`
+}
+
+// MaintenanceWindow is either a one-off range (Start/End) or a recurring
+// cron schedule (Schedule/Duration). It applies to the listed targets and to
+// every target carrying one of the listed tags.
+type MaintenanceWindow struct {
+	Name     string    `json:"name"`
+	Targets  []string  `json:"targets"`
+	Tags     []string  `json:"tags"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Schedule string    `json:"schedule"`
+	Duration Duration  `json:"duration"`
+}
+
+type AlertRule struct {
+	Name             string    `json:"name"`
+	Targets          []string  `json:"targets"`
+	Tags             []string  `json:"tags"`
+	FailureThreshold int       `json:"failure_threshold"`
+	RepeatInterval   Duration  `json:"repeat_interval"`
+	RateLimit        RateLimit `json:"rate_limit"`
+}
+
+// HostLimits caps concurrent probes and probes per second per URL host. Hosts
+// not listed in Hosts use Default.
+type HostLimits struct {
+	Default HostLimit            `json:"default"`
+	Hosts   map[string]HostLimit `json:"hosts"`
+}
+
+type HostLimit struct {
+	MaxConcurrent     int     `json:"max_concurrent"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+type RateLimit struct {
+	Count int      `json:"count"`
+	Per   Duration `json:"per"`
+}
+
+// Duration is a time.Duration that reads from JSON strings such as "5m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) Validate() error {
+	seen := make(map[string]bool)
+	for _, t := range c.Targets {
+		if t.URL == "" {
+			return fmt.Errorf("target without url")
+		}
+		if seen[t.URL] {
+			return fmt.Errorf("duplicate target %q", t.URL)
+		}
+		seen[t.URL] = true
+		if err := t.validateInterval(); err != nil {
+			return err
+		}
+		if err := t.validateBody(); err != nil {
+			return err
+		}
+		if err := t.validateTCP(); err != nil {
+			return err
+		}
+		if err := t.validateWebSocket(); err != nil {
+			return err
+		}
+		if err := t.validateSSE(); err != nil {
+			return err
+		}
+		if t.Check == "graphql" && t.GraphQL.Query == "" {
+			return fmt.Errorf("target %q: graphql check without a query", t.URL)
+		}
+		if t.Cert.WarningDays < 0 || t.Cert.CriticalDays < 0 {
+			return fmt.Errorf("target %q: certificate thresholds must not be negative", t.URL)
+		}
+		if t.Cert.WarningDays > 0 && t.Cert.CriticalDays > t.Cert.WarningDays {
+			return fmt.Errorf("target %q: critical_days must not exceed warning_days", t.URL)
+		}
+	}
+	if err := c.validateDependencies(); err != nil {
+		return err
+	}
+
+	for _, w := range c.Maintenance {
+		oneOff := !w.Start.IsZero() || !w.End.IsZero()
+		recurring := w.Schedule != ""
+		switch {
+		case oneOff && recurring:
+			return fmt.Errorf("maintenance window %q: use either start/end or schedule, not both", w.Name)
+		case oneOff && !w.End.After(w.Start):
+			return fmt.Errorf("maintenance window %q: end must be after start", w.Name)
+		case recurring && w.Duration <= 0:
+			return fmt.Errorf("maintenance window %q: recurring window needs a positive duration", w.Name)
+		case !oneOff && !recurring:
+			return fmt.Errorf("maintenance window %q: missing start/end or schedule", w.Name)
+		}
+	}
+
+	for host, l := range c.HostLimits.Hosts {
+		if l.MaxConcurrent < 0 || l.RequestsPerSecond < 0 || l.Burst < 0 {
+			return fmt.Errorf("host limit %q: limits must not be negative", host)
+		}
+	}
+	if d := c.HostLimits.Default; d.MaxConcurrent < 0 || d.RequestsPerSecond < 0 || d.Burst < 0 {
+		return fmt.Errorf("default host limit: limits must not be negative")
+	}
+
+	for _, r := range c.Alerts {
+		if r.RateLimit.Count < 0 || (r.RateLimit.Count > 0 && r.RateLimit.Per <= 0) {
+			return fmt.Errorf("alert rule %q: rate limit needs a positive count and period", r.Name)
+		}
+	}
+	return nil
+}
+
+func (t Target) validateInterval() error {
+	a := t.Adaptive
+	if t.Interval < 0 || a.MinInterval < 0 || a.MaxInterval < 0 || a.StableAfter < 0 {
+		return fmt.Errorf("target %q: intervals must not be negative", t.URL)
+	}
+	if a.MinInterval > 0 && a.MaxInterval > 0 && a.MinInterval > a.MaxInterval {
+		return fmt.Errorf("target %q: min_interval must not exceed max_interval", t.URL)
+	}
+	if t.Interval > 0 && (a.MinInterval > t.Interval || (a.MaxInterval > 0 && a.MaxInterval < t.Interval)) {
+		return fmt.Errorf("target %q: interval must lie between min_interval and max_interval", t.URL)
+	}
+	return nil
+}
+
+func (t Target) validateBody() error {
+	if t.MaxBodySize < 0 || t.Content.PrefixSize < 0 {
+		return fmt.Errorf("target %q: body sizes must not be negative", t.URL)
+	}
+	if _, err := regexp.Compile(t.Content.Matches); err != nil {
+		return fmt.Errorf("target %q: content pattern: %w", t.URL, err)
+	}
+	return nil
+}
+
+func (t Target) validateTCP() error {
+	if t.TCP.BannerSize < 0 {
+		return fmt.Errorf("target %q: banner_size must not be negative", t.URL)
+	}
+	if _, err := regexp.Compile(t.TCP.Expect); err != nil {
+		return fmt.Errorf("target %q: expect pattern: %w", t.URL, err)
+	}
+	return nil
+}
+
+func (t Target) validateWebSocket() error {
+	if t.WebSocket.Expect != "" && t.WebSocket.Send == "" {
+		return fmt.Errorf("target %q: websocket expect needs a message to send", t.URL)
+	}
+	if _, err := regexp.Compile(t.WebSocket.Expect); err != nil {
+		return fmt.Errorf("target %q: websocket expect pattern: %w", t.URL, err)
+	}
+	return nil
+}
+
+func (t Target) validateSSE() error {
+	if t.SSE.Events < 0 || t.SSE.Deadline < 0 || t.SSE.Heartbeat < 0 {
+		return fmt.Errorf("target %q: sse events, deadline and heartbeat must not be negative", t.URL)
+	}
+	if _, err := regexp.Compile(t.SSE.Match); err != nil {
+		return fmt.Errorf("target %q: sse match pattern: %w", t.URL, err)
+	}
+	return nil
+}
+
+func (c *Config) validateDependencies() error {
+	deps := c.Dependencies()
+	for url, upstreams := range deps {
+		for _, up := range upstreams {
+			if c.Target(up) == nil {
+				return fmt.Errorf("target %q depends on unknown target %q", url, up)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var visit func(url string, path []string) error
+	visit = func(url string, path []string) error {
+		switch state[url] {
+		case visiting:
+			return fmt.Errorf("circular dependency: %s", strings.Join(append(path, url), " -> "))
+		case done:
+			return nil
+		}
+		state[url] = visiting
+		for _, up := range deps[url] {
+			if err := visit(up, append(path, url)); err != nil {
+				return err
+			}
+		}
+		state[url] = done
+		return nil
+	}
+
+	for _, t := range c.Targets {
+		if err := visit(t.URL, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dependencies maps each target URL to the upstream targets it depends on.
+func (c *Config) Dependencies() map[string][]string {
+	deps := make(map[string][]string)
+	for _, t := range c.Targets {
+		if len(t.DependsOn) > 0 {
+			deps[t.URL] = t.DependsOn
+		}
+	}
+	return deps
+}
+
+// AddURLs appends plain command line URLs that are not already configured.
+func (c *Config) AddURLs(urls []string) {
+	for _, url := range urls {
+		if c.Target(url) == nil {
+			c.Targets = append(c.Targets, Target{URL: url})
+		}
+	}
+}
+
+func (c *Config) Target(url string) *Target {
+	for i := range c.Targets {
+		if c.Targets[i].URL == url {
+			return &c.Targets[i]
+		}
+	}
+	return nil
+}
+
+func (c *Config) URLs() []string {
+	urls := make([]string, 0, len(c.Targets))
+	for _, t := range c.Targets {
+		urls = append(urls, t.URL)
+	}
+	return urls
+}
+
+// Matches reports whether a target is selected by an explicit url list or by
+// any of the given tags. Empty selectors match every target.
+func Matches(target Target, urls []string, tags []string) bool {
+	if len(urls) == 0 && len(tags) == 0 {
+		return true
+	}
+	for _, u := range urls {
+		if u == target.URL {
+			return true
+		}
+	}
+	for _, tag := range tags {
+		for _, t := range target.Tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		// Test case for a complete configuration
+		// Verifies that targets, maintenance windows and alert rules are accepted
+		{
+			name: "valid config",
+			data: `{
+				"targets": [{"url": "https://example.com", "tags": ["api"]}],
+				"maintenance": [
+					{"name": "deploy", "tags": ["api"], "start": "2026-01-01T10:00:00Z", "end": "2026-01-01T11:00:00Z"},
+					{"name": "weekly", "targets": ["https://example.com"], "schedule": "0 2 * * 0", "duration": "1h"}
+				],
+				"alerts": [{"name": "down", "repeat_interval": "30m", "rate_limit": {"count": 5, "per": "1h"}}]
+			}`,
+			wantErr: false,
+		},
+		// Test case for a window mixing one-off and recurring fields
+		// Verifies that ambiguous maintenance windows are rejected
+		{
+			name:    "mixed window",
+			data:    `{"maintenance": [{"name": "x", "start": "2026-01-01T10:00:00Z", "end": "2026-01-01T11:00:00Z", "schedule": "* * * * *", "duration": "1m"}]}`,
+			wantErr: true,
+		},
+		// Test case for a recurring window without duration
+		// Verifies that recurring windows must say how long they last
+		{
+			name:    "recurring without duration",
+			data:    `{"maintenance": [{"name": "x", "schedule": "0 2 * * *"}]}`,
+			wantErr: true,
+		},
+		// Test case for a one-off window ending before it starts
+		// Verifies that inverted time ranges are rejected
+		{
+			name:    "inverted range",
+			data:    `{"maintenance": [{"name": "x", "start": "2026-01-01T11:00:00Z", "end": "2026-01-01T10:00:00Z"}]}`,
+			wantErr: true,
+		},
+		// Test case for a rate limit without a period
+		// Verifies that incomplete rate limits are rejected
+		{
+			name:    "rate limit without period",
+			data:    `{"alerts": [{"name": "x", "rate_limit": {"count": 3}}]}`,
+			wantErr: true,
+		},
+		// Test case for a dependency on a target that is not configured
+		// Verifies that dangling dependencies are rejected
+		{
+			name:    "unknown dependency",
+			data:    `{"targets": [{"url": "https://b.com", "depends_on": ["https://a.com"]}]}`,
+			wantErr: true,
+		},
+		// Test case for targets depending on each other in a loop
+		// Verifies that circular dependency configs are rejected at load time
+		{
+			name: "circular dependency",
+			data: `{"targets": [
+				{"url": "https://a.com", "depends_on": ["https://c.com"]},
+				{"url": "https://b.com", "depends_on": ["https://a.com"]},
+				{"url": "https://c.com", "depends_on": ["https://b.com"]}
+			]}`,
+			wantErr: true,
+		},
+		// Test case for a target depending on itself
+		// Verifies that self dependencies count as cycles
+		{
+			name:    "self dependency",
+			data:    `{"targets": [{"url": "https://a.com", "depends_on": ["https://a.com"]}]}`,
+			wantErr: true,
+		},
+		// Test case for a diamond shaped dependency graph
+		// Verifies that shared upstreams are not mistaken for cycles
+		{
+			name: "diamond dependency",
+			data: `{"targets": [
+				{"url": "https://gw.com"},
+				{"url": "https://a.com", "depends_on": ["https://gw.com"]},
+				{"url": "https://b.com", "depends_on": ["https://gw.com"]},
+				{"url": "https://c.com", "depends_on": ["https://a.com", "https://b.com"]}
+			]}`,
+			wantErr: false,
+		},
+		// Test case for adaptive interval bounds around the target interval
+		// Verifies that consistent interval bounds are accepted
+		{
+			name:    "adaptive interval",
+			data:    `{"targets": [{"url": "https://a.com", "interval": "30s", "adaptive": {"min_interval": "5s", "max_interval": "5m"}}]}`,
+			wantErr: false,
+		},
+		// Test case for inverted adaptive interval bounds
+		// Verifies that min_interval above max_interval is rejected
+		{
+			name:    "inverted adaptive bounds",
+			data:    `{"targets": [{"url": "https://a.com", "adaptive": {"min_interval": "1m", "max_interval": "10s"}}]}`,
+			wantErr: true,
+		},
+		// Test case for a target interval outside its bounds
+		// Verifies that the base interval must lie within the adaptive range
+		{
+			name:    "interval outside bounds",
+			data:    `{"targets": [{"url": "https://a.com", "interval": "1s", "adaptive": {"min_interval": "5s", "max_interval": "1m"}}]}`,
+			wantErr: true,
+		},
+		// Test case for a content assertion with a broken pattern
+		// Verifies that invalid regular expressions are rejected at load time
+		{
+			name:    "bad content pattern",
+			data:    `{"targets": [{"url": "https://a.com", "max_body_size": 1024, "content": {"matches": "(unclosed"}}]}`,
+			wantErr: true,
+		},
+		// Test case for a TCP check with a banner pattern
+		// Verifies that send payloads and expected banners are accepted
+		{
+			name:    "tcp banner",
+			data:    `{"targets": [{"url": "tcp://redis:6379", "tcp": {"send": "PING\r\n", "expect": "^\\+PONG"}}]}`,
+			wantErr: false,
+		},
+		// Test case for a TCP check with a broken banner pattern
+		// Verifies that invalid expect patterns are rejected at load time
+		{
+			name:    "bad tcp pattern",
+			data:    `{"targets": [{"url": "tcp://redis:6379", "tcp": {"expect": "[unclosed"}}]}`,
+			wantErr: true,
+		},
+		// Test case for certificate thresholds in the wrong order
+		// Verifies that the critical threshold may not come before the warning one
+		{
+			name:    "inverted cert thresholds",
+			data:    `{"targets": [{"url": "tls://a.com:443", "cert": {"warning_days": 7, "critical_days": 21}}]}`,
+			wantErr: true,
+		},
+		// Test case for a websocket reply pattern without a message
+		// Verifies that a reply can only be expected to a message sent
+		{
+			name:    "websocket expect without send",
+			data:    `{"targets": [{"url": "ws://rt.example.com/socket", "websocket": {"expect": "pong"}}]}`,
+			wantErr: true,
+		},
+		// Test case for a GraphQL check with variables and data assertions
+		// Verifies that queries, variables and expected values are accepted
+		{
+			name:    "graphql",
+			data:    `{"targets": [{"url": "https://api.com/graphql", "check": "graphql", "graphql": {"query": "query($id: ID!) { user(id: $id) { name } }", "variables": {"id": "1"}, "expect": {"user.name": "alice"}}}]}`,
+			wantErr: false,
+		},
+		// Test case for a GraphQL check without a query
+		// Verifies that graphql targets must say what to ask
+		{
+			name:    "graphql without query",
+			data:    `{"targets": [{"url": "https://api.com/graphql", "check": "graphql"}]}`,
+			wantErr: true,
+		},
+		// Test case for an event stream check with a heartbeat
+		// Verifies that event counts, patterns and durations are accepted
+		{
+			name:    "sse",
+			data:    `{"targets": [{"url": "https://api.com/events", "check": "sse", "sse": {"events": 2, "match": "\"type\":\\s*\"order\"", "deadline": "30s", "heartbeat": "15s"}}]}`,
+			wantErr: false,
+		},
+		// Test case for a negative event count
+		// Verifies that event streams must be waited on for a positive number of events
+		{
+			name:    "sse negative events",
+			data:    `{"targets": [{"url": "https://api.com/events", "check": "sse", "sse": {"events": -1}}]}`,
+			wantErr: true,
+		},
+		// Test case for an invalid event pattern
+		// Verifies that match patterns must compile
+		{
+			name:    "sse bad match",
+			data:    `{"targets": [{"url": "https://api.com/events", "check": "sse", "sse": {"match": "("}}]}`,
+			wantErr: true,
+		},
+		// Test case for a malformed duration
+		// Verifies that durations must be Go duration strings
+		{
+			name:    "bad duration",
+			data:    `{"alerts": [{"name": "x", "repeat_interval": "soon"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_AddURLs(t *testing.T) {
+	// Test case for merging command line URLs into a config
+	// Verifies that configured targets keep their settings and new URLs are appended once
+	cfg, err := Parse([]byte(`{"targets": [{"url": "https://a.com", "tags": ["x"]}], "alerts": [{"name": "r", "repeat_interval": "1m"}]}`))
+	require.NoError(t, err)
+
+	cfg.AddURLs([]string{"https://a.com", "https://b.com"})
+
+	assert.Equal(t, []string{"https://a.com", "https://b.com"}, cfg.URLs())
+	assert.Equal(t, []string{"x"}, cfg.Target("https://a.com").Tags)
+	assert.Equal(t, Duration(time.Minute), cfg.Alerts[0].RepeatInterval)
+}
+
+func TestMatches(t *testing.T) {
+	target := Target{URL: "https://a.com", Tags: []string{"api", "prod"}}
+
+	assert.True(t, Matches(target, nil, nil))
+	assert.True(t, Matches(target, []string{"https://a.com"}, nil))
+	assert.True(t, Matches(target, nil, []string{"prod"}))
+	assert.False(t, Matches(target, []string{"https://b.com"}, []string{"staging"}))
+}
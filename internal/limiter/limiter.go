@@ -0,0 +1,207 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limits bounds the probes sent to a single host. Zero values mean unlimited.
+type Limits struct {
+	MaxConcurrent     int
+	RequestsPerSecond float64
+	// Burst is the number of requests allowed back to back before the rate
+	// applies. It defaults to 1.
+	Burst int
+}
+
+type host struct {
+	slots chan struct{}
+
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// waiters are closed, one per released slot, to wake the callers that
+	// TryAcquire turned away for want of a slot.
+	waiters []chan struct{}
+}
+
+func newHost(limits Limits, now time.Time) *host {
+	h := &host{rate: limits.RequestsPerSecond, last: now}
+	if limits.MaxConcurrent > 0 {
+		h.slots = make(chan struct{}, limits.MaxConcurrent)
+	}
+	if h.rate > 0 {
+		h.burst = float64(limits.Burst)
+		if h.burst < 1 {
+			h.burst = 1
+		}
+		h.tokens = h.burst
+	}
+	return h
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// using it.
+func (h *host) reserve(now time.Time) time.Duration {
+	if h.rate <= 0 {
+		return 0
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.tokens += now.Sub(h.last).Seconds() * h.rate
+	if h.tokens > h.burst {
+		h.tokens = h.burst
+	}
+	h.last = now
+	h.tokens--
+	if h.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-h.tokens / h.rate * float64(time.Second))
+}
+
+// release frees a slot and wakes the longest waiting caller of TryAcquire.
+func (h *host) release() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	<-h.slots
+	if len(h.waiters) > 0 {
+		close(h.waiters[0])
+		h.waiters = h.waiters[1:]
+	}
+}
+
+// trySlot takes a free slot, or returns a channel closed once one is
+// released. Both happen under the mutex so that no release goes unnoticed.
+func (h *host) trySlot() (<-chan struct{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	select {
+	case h.slots <- struct{}{}:
+		return nil, true
+	default:
+	}
+	ready := make(chan struct{})
+	h.waiters = append(h.waiters, ready)
+	return ready, false
+}
+
+func (h *host) cancel() {
+	if h.rate <= 0 {
+		return
+	}
+	h.mutex.Lock()
+	h.tokens++
+	h.mutex.Unlock()
+}
+
+// HostLimiter applies per-host concurrency and rate limits. Hosts without an
+// explicit entry share the default limits, each with its own budget.
+type HostLimiter struct {
+	defaults Limits
+	perHost  map[string]Limits
+
+	mutex sync.Mutex
+	hosts map[string]*host
+	now   func() time.Time
+}
+
+func New(defaults Limits, perHost map[string]Limits) *HostLimiter {
+	return &HostLimiter{
+		defaults: defaults,
+		perHost:  perHost,
+		hosts:    make(map[string]*host),
+		now:      time.Now,
+	}
+}
+
+func (l *HostLimiter) host(name string) *host {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	h, ok := l.hosts[name]
+	if !ok {
+		limits, ok := l.perHost[name]
+		if !ok {
+			limits = l.defaults
+		}
+		h = newHost(limits, l.now())
+		l.hosts[name] = h
+	}
+	return h
+}
+
+// Acquire blocks until a probe to the host may start. It returns a release
+// function that must be called when the probe is done, and the time spent
+// waiting. A nil HostLimiter never waits.
+func (l *HostLimiter) Acquire(ctx context.Context, name string) (func(), time.Duration, error) {
+	if l == nil {
+		return func() {}, 0, nil
+	}
+
+	h := l.host(name)
+	start := l.now()
+
+	if wait := h.reserve(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			h.cancel()
+			return nil, l.now().Sub(start), ctx.Err()
+		}
+	}
+
+	if h.slots == nil {
+		return func() {}, l.now().Sub(start), nil
+	}
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, l.now().Sub(start), ctx.Err()
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(h.release)
+	}
+	return release, l.now().Sub(start), nil
+}
+
+// TryAcquire is Acquire without waiting. When the host is over its limits it
+// takes nothing. It then returns how long to wait for the rate limit or, when
+// all of the host's slots are taken, a channel closed once one is released.
+func (l *HostLimiter) TryAcquire(name string) (func(), time.Duration, <-chan struct{}, bool) {
+	if l == nil {
+		return func() {}, 0, nil, true
+	}
+
+	h := l.host(name)
+	if h.slots != nil {
+		if ready, ok := h.trySlot(); !ok {
+			return nil, 0, ready, false
+		}
+	}
+	if wait := h.reserve(l.now()); wait > 0 {
+		h.cancel()
+		if h.slots != nil {
+			h.release()
+		}
+		return nil, wait, nil, false
+	}
+
+	if h.slots == nil {
+		return func() {}, 0, nil, true
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(h.release)
+	}
+	return release, 0, nil, true
+}
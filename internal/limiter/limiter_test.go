@@ -0,0 +1,184 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLimiter_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	// Test case for many parallel probes against one host
+	// Verifies that no more than the configured number run at the same time
+	l := New(Limits{MaxConcurrent: 2}, nil)
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, _, err := l.Acquire(context.Background(), "api.example.com")
+			require.NoError(t, err)
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestHostLimiter_Rate(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a host limited to a number of requests per second
+	// Verifies that requests beyond the burst are delayed and the delay is reported
+	l := New(Limits{RequestsPerSecond: 100, Burst: 2}, nil)
+
+	var waits []time.Duration
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		release, wait, err := l.Acquire(context.Background(), "api.example.com")
+		require.NoError(t, err)
+		release()
+		waits = append(waits, wait)
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	assert.Less(t, waits[0], 5*time.Millisecond)
+	assert.Less(t, waits[1], 5*time.Millisecond)
+	assert.Greater(t, waits[2], 5*time.Millisecond)
+}
+
+func TestHostLimiter_PerHostOverrides(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a host with its own limits next to default limited hosts
+	// Verifies that each host gets its own budget and overrides replace the defaults
+	l := New(Limits{MaxConcurrent: 1}, map[string]Limits{"big.example.com": {MaxConcurrent: 3}})
+
+	var releases []func()
+	for i := 0; i < 3; i++ {
+		release, _, err := l.Acquire(context.Background(), "big.example.com")
+		require.NoError(t, err)
+		releases = append(releases, release)
+	}
+
+	releaseA, _, err := l.Acquire(context.Background(), "a.example.com")
+	require.NoError(t, err)
+	releaseB, _, err := l.Acquire(context.Background(), "b.example.com")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, wait, err := l.Acquire(ctx, "a.example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, wait, 15*time.Millisecond)
+
+	releaseA()
+	releaseB()
+	for _, release := range releases {
+		release()
+	}
+}
+
+func TestHostLimiter_CancelledRateWaitRefundsToken(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a probe cancelled while waiting for the rate limit
+	// Verifies that the reserved token is returned to the bucket
+	l := New(Limits{RequestsPerSecond: 1}, nil)
+
+	release, _, err := l.Acquire(context.Background(), "h")
+	require.NoError(t, err)
+	release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = l.Acquire(ctx, "h")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.InDelta(t, 0.0, l.host("h").tokens, 0.1)
+}
+
+func TestHostLimiter_Nil(t *testing.T) {
+	// Test case for a monitor running without host limits
+	// Verifies that a nil limiter never blocks
+	var l *HostLimiter
+	release, wait, err := l.Acquire(context.Background(), "h")
+	require.NoError(t, err)
+	release()
+	assert.Zero(t, wait)
+}
+
+func TestHostLimiter_TryAcquire(t *testing.T) {
+	t.Parallel()
+
+	// Test case for probes asking a host's limits without waiting
+	// Verifies that a probe over the limits takes nothing and is told when to try again
+	l := New(Limits{MaxConcurrent: 1, RequestsPerSecond: 10}, nil)
+
+	release, wait, ready, ok := l.TryAcquire("h")
+	require.True(t, ok)
+	assert.Zero(t, wait)
+	assert.Nil(t, ready)
+
+	_, wait, ready, ok = l.TryAcquire("h")
+	assert.False(t, ok)
+	assert.Zero(t, wait)
+	require.NotNil(t, ready)
+	select {
+	case <-ready:
+		t.Fatal("woken before a slot was released")
+	default:
+	}
+	release()
+	<-ready
+
+	_, wait, ready, ok = l.TryAcquire("h")
+	assert.False(t, ok)
+	assert.Nil(t, ready)
+	assert.InDelta(t, 100*time.Millisecond, wait, float64(10*time.Millisecond))
+	assert.Len(t, l.host("h").slots, 0)
+	assert.InDelta(t, 0.0, l.host("h").tokens, 0.1)
+}
+
+func TestHostLimiter_TryAcquireWakesOneWaiter(t *testing.T) {
+	t.Parallel()
+
+	// Test case for several probes waiting for a host's only slot
+	// Verifies that each release wakes the longest waiting probe and no other
+	l := New(Limits{MaxConcurrent: 1}, nil)
+
+	release, _, _, ok := l.TryAcquire("h")
+	require.True(t, ok)
+	_, _, first, _ := l.TryAcquire("h")
+	_, _, second, _ := l.TryAcquire("h")
+
+	release()
+	<-first
+	select {
+	case <-second:
+		t.Fatal("second waiter woken by the first release")
+	default:
+	}
+
+	release, _, _, ok = l.TryAcquire("h")
+	require.True(t, ok)
+	release()
+	<-second
+}
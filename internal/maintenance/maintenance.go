@@ -0,0 +1,73 @@
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+)
+
+type window struct {
+	cfg  config.MaintenanceWindow
+	cron *cronSchedule
+}
+
+func (w *window) activeAt(now time.Time) bool {
+	if w.cron == nil {
+		return !now.Before(w.cfg.Start) && now.Before(w.cfg.End)
+	}
+	// The window is open when the latest activation happened less than one
+	// duration ago.
+	start := w.cron.Next(now.Add(-time.Duration(w.cfg.Duration)))
+	return !start.IsZero() && !start.After(now)
+}
+
+// Schedule answers whether a target is inside any configured maintenance
+// window. A nil Schedule never reports maintenance.
+type Schedule struct {
+	windows []window
+	targets map[string]config.Target
+}
+
+func New(windows []config.MaintenanceWindow, targets []config.Target) (*Schedule, error) {
+	s := &Schedule{targets: make(map[string]config.Target)}
+	for _, t := range targets {
+		s.targets[t.URL] = t
+	}
+
+	for _, w := range windows {
+		compiled := window{cfg: w}
+		if w.Schedule != "" {
+			c, err := parseCron(w.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance window %q: %w", w.Name, err)
+			}
+			compiled.cron = c
+		}
+		s.windows = append(s.windows, compiled)
+	}
+	return s, nil
+}
+
+// Active returns the name of the first open window covering the target.
+func (s *Schedule) Active(url string, now time.Time) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	target, ok := s.targets[url]
+	if !ok {
+		target = config.Target{URL: url}
+	}
+
+	for i := range s.windows {
+		w := &s.windows[i]
+		if !config.Matches(target, w.cfg.Targets, w.cfg.Tags) {
+			continue
+		}
+		if w.activeAt(now) {
+			return w.cfg.Name, true
+		}
+	}
+	return "", false
+}
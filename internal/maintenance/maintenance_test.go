@@ -0,0 +1,177 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		from     string
+		expected string
+	}{
+		// Test case for an every-minute schedule
+		// Verifies that the next minute boundary is returned
+		{
+			name:     "every minute",
+			expr:     "* * * * *",
+			from:     "2026-03-04T10:15:30Z",
+			expected: "2026-03-04T10:16:00Z",
+		},
+		// Test case for a daily schedule that already ran today
+		// Verifies that the activation rolls over to the next day
+		{
+			name:     "daily rollover",
+			expr:     "30 2 * * *",
+			from:     "2026-03-04T10:00:00Z",
+			expected: "2026-03-05T02:30:00Z",
+		},
+		// Test case for a weekly schedule on Sundays
+		// Verifies that day-of-week restrictions are honoured
+		{
+			name:     "weekly sunday",
+			expr:     "0 3 * * 0",
+			from:     "2026-03-04T10:00:00Z", // Wednesday
+			expected: "2026-03-08T03:00:00Z",
+		},
+		// Test case for Sunday written as 7
+		// Verifies that both cron spellings of Sunday are accepted
+		{
+			name:     "sunday as seven",
+			expr:     "0 3 * * 7",
+			from:     "2026-03-04T10:00:00Z",
+			expected: "2026-03-08T03:00:00Z",
+		},
+		// Test case for ranges, lists and steps
+		// Verifies that composite field syntax is parsed correctly
+		{
+			name:     "ranges and steps",
+			expr:     "*/15 9-17 1,15 * *",
+			from:     "2026-03-01T17:50:00Z",
+			expected: "2026-03-15T09:00:00Z",
+		},
+		// Test case for a single value with a step
+		// Verifies that the step repeats from the value to the end of the field
+		{
+			name:     "value with step",
+			expr:     "5/10 * * * *",
+			from:     "2026-03-04T10:15:30Z",
+			expected: "2026-03-04T10:25:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseCron(tt.expr)
+			require.NoError(t, err)
+
+			from, _ := time.Parse(time.RFC3339, tt.from)
+			expected, _ := time.Parse(time.RFC3339, tt.expected)
+			assert.Equal(t, expected, s.Next(from))
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := parseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Active(t *testing.T) {
+	targets := []config.Target{
+		{URL: "https://api.com", Tags: []string{"api"}},
+		{URL: "https://web.com", Tags: []string{"web"}},
+	}
+	windows := []config.MaintenanceWindow{
+		{
+			Name:  "deploy",
+			Tags:  []string{"api"},
+			Start: time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC),
+			End:   time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:     "nightly",
+			Targets:  []string{"https://web.com"},
+			Schedule: "0 2 * * *",
+			Duration: config.Duration(30 * time.Minute),
+		},
+	}
+
+	s, err := New(windows, targets)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		url        string
+		at         time.Time
+		wantActive bool
+		wantWindow string
+	}{
+		// Test case for a tagged target inside a one-off window
+		// Verifies that tag selectors put the target into maintenance
+		{
+			name:       "one-off by tag",
+			url:        "https://api.com",
+			at:         time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+			wantActive: true,
+			wantWindow: "deploy",
+		},
+		// Test case for the end boundary of a one-off window
+		// Verifies that the end time is exclusive
+		{
+			name:       "one-off end exclusive",
+			url:        "https://api.com",
+			at:         time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC),
+			wantActive: false,
+		},
+		// Test case for a target not selected by the window
+		// Verifies that other targets are unaffected
+		{
+			name:       "unrelated target",
+			url:        "https://web.com",
+			at:         time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+			wantActive: false,
+		},
+		// Test case for a time inside a recurring window
+		// Verifies that the window stays open for its duration after the cron activation
+		{
+			name:       "recurring inside",
+			url:        "https://web.com",
+			at:         time.Date(2026, 3, 5, 2, 29, 59, 0, time.UTC),
+			wantActive: true,
+			wantWindow: "nightly",
+		},
+		// Test case for a time after a recurring window closed
+		// Verifies that the window closes after its duration
+		{
+			name:       "recurring after",
+			url:        "https://web.com",
+			at:         time.Date(2026, 3, 5, 2, 30, 0, 0, time.UTC),
+			wantActive: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, active := s.Active(tt.url, tt.at)
+			assert.Equal(t, tt.wantActive, active)
+			assert.Equal(t, tt.wantWindow, window)
+		})
+	}
+}
+
+func TestSchedule_Nil(t *testing.T) {
+	// Test case for a monitor running without maintenance configuration
+	// Verifies that a nil schedule never reports maintenance
+	var s *Schedule
+	_, active := s.Active("https://example.com", time.Now())
+	assert.False(t, active)
+}
@@ -2,182 +2,317 @@ package monitor
 
 import (
 	"context"
-	"io"
+	"errors"
 	"net/http"
+	neturl "net/url"
 	"sync"
 	"time"
 
+	"github.com/dvdk01/http-status-monitor/internal/limiter"
+	"github.com/dvdk01/http-status-monitor/internal/maintenance"
+	"github.com/dvdk01/http-status-monitor/internal/probe"
+	"github.com/dvdk01/http-status-monitor/internal/publisher"
+	"github.com/dvdk01/http-status-monitor/internal/retry"
+	"github.com/dvdk01/http-status-monitor/internal/scheduler"
 	"github.com/dvdk01/http-status-monitor/internal/schema"
 )
 
 type httpMonitor struct {
 	urls      []string
 	client    *http.Client
-	stats     map[string]*schema.URLStats
-	mutex     sync.RWMutex
-	statsChan chan map[string]*schema.URLStats
+	stats     *statsStore
+	publisher *publisher.Publisher
+
+	maintenance  *maintenance.Schedule
+	dependencies map[string][]string
+	onResult     []func(schema.RequestResult)
+	initial      map[string]*schema.URLStats
+
+	interval  time.Duration
+	intervals map[string]IntervalPolicy
+	timeout   time.Duration
+	workers   int
+	stagger   time.Duration
+	jitter    time.Duration
+	limiter   *limiter.HostLimiter
+	retries   map[string]retry.Policy
+
+	probes *probe.Registry
+	checks map[string]string
+
+	renderInterval time.Duration
+
+	lifecycle sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
 }
 
+// Start probes the URLs until ctx is done or Stop is called. In-flight
+// probes are cancelled and their results discarded.
 func (m *httpMonitor) Start(ctx context.Context) error {
+	m.lifecycle.Lock()
+	if m.done != nil {
+		m.lifecycle.Unlock()
+		return errors.New("monitor already started")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	m.cancel, m.done = cancel, make(chan struct{})
+	m.lifecycle.Unlock()
+	defer close(m.done)
+	defer cancel()
 
-	for _, url := range m.urls {
-		m.stats[url] = &schema.URLStats{
-			URL:         url,
-			StatusCodes: make(map[int]int),
-			MinDuration: time.Duration(^uint64(0) >> 1), // math.Maxint alternative (to avoid dependency on math package)
-			MinPayload:  int(^uint(0) >> 1),             // math.Maxint alternative (to avoid dependency on math package)
-		}
+	s := scheduler.New(m.workers, scheduler.WithJitter(m.jitter))
+	for i, url := range m.urls {
+		// Spread the first probes evenly over the stagger window.
+		s.Add(m.job(url, m.stagger*time.Duration(i)/time.Duration(len(m.urls))))
 	}
 
 	var wg sync.WaitGroup
-	for _, url := range m.urls {
-		wg.Add(1)
-		go m.monitorURL(ctx, url, &wg, 5*time.Second, 10*time.Second)
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.publisher.Run(ctx)
+	}()
 
+	s.Run(ctx)
 	wg.Wait()
 	return nil
 }
 
+// Subscribe returns a channel receiving the latest stats after probes and a
+// function cancelling the subscription. The channel is closed once the
+// monitor stops.
+func (m *httpMonitor) Subscribe() (<-chan map[string]*schema.URLStats, func()) {
+	return m.publisher.Subscribe()
+}
+
+// Stop cancels a running Start and waits for it to return.
 func (m *httpMonitor) Stop() error {
+	m.lifecycle.Lock()
+	cancel, done := m.cancel, m.done
+	m.lifecycle.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
 	return nil
 }
 
 func (m *httpMonitor) GetStats() map[string]*schema.URLStats {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
-	//Generate deepcopy of stats
-	stats := make(map[string]*schema.URLStats)
-	for k, v := range m.stats {
-		stats[k] = v
-		stats[k] = &schema.URLStats{
-			URL:           v.URL,
-			TotalRequests: v.TotalRequests,
-			SuccessCount:  v.SuccessCount,
-			MinDuration:   v.MinDuration,
-			MaxDuration:   v.MaxDuration,
-			TotalDuration: v.TotalDuration,
-			MinPayload:    v.MinPayload,
-			MaxPayload:    v.MaxPayload,
-			TotalPayload:  v.TotalPayload,
-			StatusCodes:   make(map[int]int),
-		}
-		for code, count := range v.StatusCodes {
-			stats[k].StatusCodes[code] = count
-		}
-	}
-	return stats
+	return m.stats.snapshot()
 }
 
-func (m *httpMonitor) monitorURL(ctx context.Context, url string, wg *sync.WaitGroup, interval time.Duration, timeout time.Duration) {
-	defer wg.Done()
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+func (m *httpMonitor) GetStatsSince(version uint64) (map[string]*schema.URLStats, uint64) {
+	return m.stats.since(version)
+}
 
-	// Execute initial request immediately without waiting for the first tick
-	result := m.makeRequest(url, timeout)
-	m.updateStats(result)
-	go func() {
-		m.statsChan <- m.GetStats()
-	}()
+func (m *httpMonitor) intervalPolicy(url string) IntervalPolicy {
+	policy := m.intervals[url]
+	if policy.Base <= 0 {
+		policy.Base = m.interval
+	}
+	return policy
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			result := m.makeRequest(url, timeout)
-			m.updateStats(result)
-			go func() {
-				m.statsChan <- m.GetStats()
-			}()
+// admission is the host limiter's permission for a probe's first attempt,
+// taken before the probe was given a worker.
+type admission struct {
+	release func()
+	waited  time.Duration
+}
 
-		}
+// job schedules the probes of url. The host limiter is asked when a probe is
+// dispatched, and a probe over its host's limits goes back to the scheduler
+// instead of holding a worker that other hosts could use. It is due again
+// when the rate limit allows or, waiting for a slot, once one is released.
+func (m *httpMonitor) job(url string, delay time.Duration) scheduler.Job {
+	host := hostOf(url)
+	var admitted *admission
+	var waitingSince time.Time
+	return scheduler.Job{
+		Key:      url,
+		Interval: m.intervalPolicy(url).Base,
+		Delay:    delay,
+		Admit: func() (time.Duration, <-chan struct{}) {
+			now := time.Now()
+			release, wait, ready, ok := m.limiter.TryAcquire(host)
+			if !ok {
+				if waitingSince.IsZero() {
+					waitingSince = now
+				}
+				return wait, ready
+			}
+			admitted = &admission{release: release}
+			if !waitingSince.IsZero() {
+				admitted.waited = now.Sub(waitingSince)
+				waitingSince = time.Time{}
+			}
+			return 0, nil
+		},
+		Run: func(ctx context.Context, lag time.Duration) time.Duration {
+			first := admitted
+			admitted = nil
+			return m.probeAdmitted(ctx, url, lag, first)
+		},
 	}
 }
 
-func (m *httpMonitor) makeRequest(url string, timeout time.Duration) schema.RequestResult {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+// probeAdmitted records one result for url and returns the interval until
+// the next probe. The first attempt was already admitted by the host limiter
+// when first is set; retries wait for the limiter.
+func (m *httpMonitor) probeAdmitted(ctx context.Context, url string, lag time.Duration, first *admission) time.Duration {
+	policy := m.retries[url]
+	host := hostOf(url)
+	target := probe.Target{URL: url, Check: m.checkType(url), Timeout: m.timeout}
+	m.stats.read(url, func(stats *schema.URLStats) {
+		target.Sequence = stats.TotalRequests
+	})
+
+	var result schema.RequestResult
+	var waited time.Duration
+	attempt := 1
+	for ; ; attempt++ {
+		var release func()
+		var wait time.Duration
+		var err error
+		if attempt == 1 && first != nil {
+			release, wait = first.release, first.waited
+		} else {
+			release, wait, err = m.limiter.Acquire(ctx, host)
+		}
+		waited += wait
+		if err != nil {
+			if attempt == 1 {
+				// Shutting down while waiting for the host; nothing was probed.
+				return 0
+			}
+			attempt--
+			break
+		}
+		res := m.probes.Run(ctx, target)
+		release()
+		if res.Error != nil && ctx.Err() != nil {
+			// Cancelled by shutdown; the attempt says nothing about the target.
+			if attempt == 1 {
+				return 0
+			}
+			attempt--
+			break
+		}
+		result = res
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return schema.RequestResult{
-			URL:     url,
-			Error:   err,
-			Success: false,
+		if attempt >= policy.MaxAttempts() || !policy.ShouldRetry(result) {
+			break
 		}
+		backoff := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-backoff.C:
+			continue
+		case <-ctx.Done():
+			backoff.Stop()
+		}
+		break
 	}
 
-	start := time.Now()
-	resp, err := m.client.Do(req)
-	duration := time.Since(start)
+	result.Attempts = attempt
+	result.SchedulingLag = lag
+	result.LimiterDelay = waited
+	m.handleResult(result)
+	m.publisher.Notify()
+
+	var next time.Duration
+	m.stats.read(url, func(stats *schema.URLStats) {
+		next = stats.EffectiveInterval
+	})
+	return next
+}
 
-	result := schema.RequestResult{
-		URL:      url,
-		Duration: duration,
+// hostOf returns the key of a URL's host limits: its host, or for unix://
+// targets the socket, as in unix:/run/app.sock.
+func hostOf(rawURL string) string {
+	if socket, ok := probe.UnixSocket(rawURL); ok {
+		return "unix:" + socket
 	}
-
+	u, err := neturl.Parse(rawURL)
 	if err != nil {
-		result.Error = err
-		result.Success = false
-		return result
+		return rawURL
 	}
-	defer resp.Body.Close() //nolint
+	return u.Host
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		result.PayloadSize = 0
+// checkType returns the configured check type of url, falling back to the
+// one implied by its scheme.
+func (m *httpMonitor) checkType(url string) string {
+	if check, ok := m.checks[url]; ok && check != "" {
+		return check
 	}
-	result.PayloadSize = len(body)
-
-	result.Status = resp.StatusCode
-	result.Success = resp.StatusCode >= 200 && resp.StatusCode < 400
-
-	return result
+	return probe.CheckType(url)
 }
 
-func (m *httpMonitor) updateStats(result schema.RequestResult) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	stats := m.stats[result.URL]
-	stats.TotalRequests++
-
-	if result.Success {
-		stats.SuccessCount++
+func (m *httpMonitor) handleResult(result schema.RequestResult) {
+	if !result.Success {
+		result.UpstreamDown = m.downUpstream(result.URL)
 	}
-
-	if result.Duration < stats.MinDuration {
-		stats.MinDuration = result.Duration
+	m.updateStats(result)
+	for _, handler := range m.onResult {
+		handler(result)
 	}
-	if result.Duration > stats.MaxDuration {
-		stats.MaxDuration = result.Duration
+}
+
+func (m *httpMonitor) downUpstream(url string) string {
+	for _, upstream := range m.dependencies[url] {
+		var down bool
+		m.stats.read(upstream, func(stats *schema.URLStats) {
+			down = stats.IsDown()
+		})
+		if down {
+			return upstream
+		}
 	}
+	return ""
+}
 
-	stats.TotalDuration += result.Duration
+func (m *httpMonitor) updateStats(result schema.RequestResult) {
+	_, inMaintenance := m.maintenance.Active(result.URL, time.Now())
+	policy := m.intervalPolicy(result.URL)
+	m.stats.update(result.URL, func(stats *schema.URLStats) {
+		stats.Add(result)
+		stats.InMaintenance = inMaintenance
+		stats.EffectiveInterval = policy.Next(stats)
+	})
+}
 
-	if result.PayloadSize < stats.MinPayload {
-		stats.MinPayload = result.PayloadSize
+func NewMonitor(client *http.Client, urls []string, opts ...Option) Monitor {
+	m := &httpMonitor{
+		client:   client,
+		urls:     urls,
+		interval: 5 * time.Second,
+		timeout:  10 * time.Second,
+		workers:  DefaultWorkers,
 	}
-	if result.PayloadSize > stats.MaxPayload {
-		stats.MaxPayload = result.PayloadSize
+	for _, opt := range opts {
+		opt(m)
 	}
 
-	stats.TotalPayload += result.PayloadSize
-
-	if result.Status > 0 {
-		stats.StatusCodes[result.Status]++
+	stats := make(map[string]*schema.URLStats, len(urls))
+	for _, url := range urls {
+		if restored, ok := m.initial[url]; ok {
+			copied := *restored
+			stats[url] = &copied
+		} else {
+			stats[url] = schema.NewURLStats(url)
+		}
+		stats[url].EffectiveInterval = m.intervalPolicy(url).Base
 	}
-}
+	m.stats = newStatsStore(stats)
 
-func NewMonitor(client *http.Client, urls []string, statsChan chan map[string]*schema.URLStats) Monitor {
-	return &httpMonitor{
-		client:    client,
-		stats:     make(map[string]*schema.URLStats),
-		urls:      urls,
-		statsChan: statsChan,
+	if m.probes == nil {
+		m.probes = probe.NewRegistry()
+		m.probes.Register(probe.CheckHTTP, probe.NewHTTP(client))
 	}
+
+	m.publisher = publisher.New(m.GetStats, publisher.WithMinInterval(m.renderInterval))
+	return m
 }
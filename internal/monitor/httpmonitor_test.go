@@ -1,126 +1,25 @@
 package monitor
 
 import (
-	"errors"
-	"io"
+	"context"
 	"net/http"
-	"strings"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/limiter"
+	"github.com/dvdk01/http-status-monitor/internal/maintenance"
+	"github.com/dvdk01/http-status-monitor/internal/probe"
+	"github.com/dvdk01/http-status-monitor/internal/retry"
 	"github.com/dvdk01/http-status-monitor/internal/schema"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestHTTPMonitor_makeRequest(t *testing.T) {
-	t.Parallel()
-
-	tests := []struct {
-		name         string
-		url          string
-		mockResponse *http.Response
-		mockError    error
-		expected     schema.RequestResult
-	}{
-		// Test case for successful HTTP request with 200 status code
-		// Verifies that the monitor correctly processes a successful response
-		// and sets the appropriate success flag and status code
-		{
-			name: "successful request",
-			url:  "http://example.com/success",
-			mockResponse: &http.Response{
-				StatusCode: 200,
-				Body:       io.NopCloser(strings.NewReader("test response")),
-			},
-			mockError: nil,
-			expected: schema.RequestResult{
-				URL:     "http://example.com/success",
-				Status:  200,
-				Success: true,
-			},
-		},
-		// Test case for failed HTTP request with 404 status code
-		// Verifies that the monitor correctly handles a not found response
-		// and sets the success flag to false while preserving the status code
-		{
-			name: "404 response",
-			url:  "http://example.com/notfound",
-			mockResponse: &http.Response{
-				StatusCode: 404,
-				Body:       io.NopCloser(strings.NewReader("not found")),
-			},
-			mockError: nil,
-			expected: schema.RequestResult{
-				URL:     "http://example.com/notfound",
-				Status:  404,
-				Success: false,
-			},
-		},
-		// Test case for network error during HTTP request
-		// Verifies that the monitor correctly handles connection errors
-		// and sets appropriate error state and success flag
-		{
-			name:         "request error",
-			url:          "http://error.com",
-			mockResponse: nil,
-			mockError:    errors.New("connection refused"),
-			expected: schema.RequestResult{
-				URL:     "http://error.com",
-				Success: false,
-				Error:   errors.New("connection refused"),
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-
-			// Create a new transport for each test
-			transport := httpmock.NewMockTransport()
-			client := &http.Client{Transport: transport}
-			defer transport.Reset()
-
-			// Register responder for this test
-			if tt.mockError != nil {
-				transport.RegisterResponder("GET", tt.url,
-					func(req *http.Request) (*http.Response, error) {
-						return nil, tt.mockError
-					},
-				)
-			} else {
-				transport.RegisterResponder("GET", tt.url,
-					func(req *http.Request) (*http.Response, error) {
-						return tt.mockResponse, nil
-					},
-				)
-			}
-
-			monitor := &httpMonitor{
-				client: client,
-				stats:  make(map[string]*schema.URLStats),
-			}
-
-			result := monitor.makeRequest(tt.url, time.Second)
-
-			// Ověření základních vlastností
-			assert.Equal(t, tt.expected.URL, result.URL)
-			if tt.mockError != nil {
-				assert.False(t, result.Success)
-				assert.Equal(t, 0, result.Status)
-				assert.Error(t, result.Error)
-			} else {
-				assert.Equal(t, tt.expected.Success, result.Success)
-				assert.Equal(t, tt.expected.Status, result.Status)
-				assert.NoError(t, result.Error)
-			}
-			assert.NotZero(t, result.Duration)
-		})
-	}
-}
-
 func TestHTTPMonitor_updateStats(t *testing.T) {
 	t.Parallel()
 
@@ -214,13 +113,13 @@ func TestHTTPMonitor_updateStats(t *testing.T) {
 			t.Parallel()
 
 			monitor := &httpMonitor{
-				stats: tt.initialStats,
+				stats: newStatsStore(tt.initialStats),
 			}
 
 			monitor.updateStats(tt.result)
 
 			// Ověření statistik
-			stats := monitor.stats[tt.result.URL]
+			stats := monitor.GetStats()[tt.result.URL]
 			expected := tt.expectedStats[tt.result.URL]
 
 			assert.Equal(t, expected.TotalRequests, stats.TotalRequests)
@@ -258,7 +157,7 @@ func TestHTTPMonitor_GetStats(t *testing.T) {
 	}
 
 	monitor := &httpMonitor{
-		stats: initialStats,
+		stats: newStatsStore(initialStats),
 	}
 
 	stats := monitor.GetStats()
@@ -277,3 +176,414 @@ func TestHTTPMonitor_GetStats(t *testing.T) {
 	stats["http://example.com"].TotalRequests = 2
 	assert.Equal(t, 1, initialStats["http://example.com"].TotalRequests)
 }
+
+func TestHTTPMonitor_updateStatsMaintenance(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a probe of a target inside a maintenance window
+	// Verifies that the stats are flagged while the window is open
+	schedule, err := maintenance.New([]config.MaintenanceWindow{{
+		Name:  "deploy",
+		Start: time.Now().Add(-time.Minute),
+		End:   time.Now().Add(time.Hour),
+	}}, nil)
+	assert.NoError(t, err)
+
+	monitor := &httpMonitor{
+		stats: newStatsStore(map[string]*schema.URLStats{
+			"http://example.com": {URL: "http://example.com", StatusCodes: make(map[int]int)},
+		}),
+		maintenance: schedule,
+	}
+
+	monitor.updateStats(schema.RequestResult{URL: "http://example.com", Success: false})
+
+	assert.True(t, monitor.GetStats()["http://example.com"].InMaintenance)
+}
+
+func TestHTTPMonitor_handleResultUpstreamDown(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a dependent URL failing while its upstream is down
+	// Verifies that the failure is tagged with the upstream in the result and stats
+	var handled []schema.RequestResult
+	monitor := &httpMonitor{
+		stats: newStatsStore(map[string]*schema.URLStats{
+			"http://gateway.com": {URL: "http://gateway.com", StatusCodes: make(map[int]int)},
+			"http://service.com": {URL: "http://service.com", StatusCodes: make(map[int]int)},
+		}),
+		dependencies: map[string][]string{"http://service.com": {"http://gateway.com"}},
+		onResult: []func(schema.RequestResult){func(r schema.RequestResult) {
+			handled = append(handled, r)
+		}},
+	}
+
+	monitor.handleResult(schema.RequestResult{URL: "http://service.com", Success: false})
+	assert.Empty(t, monitor.GetStats()["http://service.com"].UpstreamDown)
+
+	monitor.handleResult(schema.RequestResult{URL: "http://gateway.com", Success: false})
+	monitor.handleResult(schema.RequestResult{URL: "http://service.com", Success: false})
+
+	assert.Equal(t, "http://gateway.com", handled[2].UpstreamDown)
+	assert.Equal(t, "http://gateway.com", monitor.GetStats()["http://service.com"].UpstreamDown)
+	assert.Equal(t, 1, monitor.GetStats()["http://service.com"].UpstreamDownCount)
+	assert.Empty(t, monitor.GetStats()["http://gateway.com"].UpstreamDown)
+
+	monitor.handleResult(schema.RequestResult{URL: "http://gateway.com", Success: true, Status: 200})
+	monitor.handleResult(schema.RequestResult{URL: "http://service.com", Success: false})
+
+	assert.Empty(t, handled[4].UpstreamDown)
+	assert.Empty(t, monitor.GetStats()["http://service.com"].UpstreamDown)
+	assert.Equal(t, 3, monitor.GetStats()["http://service.com"].ConsecutiveFailures)
+}
+
+func TestHTTPMonitor_StartWithInitialStats(t *testing.T) {
+	t.Parallel()
+
+	// Test case for resuming from a saved snapshot
+	// Verifies that counters continue from the restored values instead of restarting at zero
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://example.com", httpmock.NewStringResponder(200, "ok"))
+
+	restored := map[string]*schema.URLStats{
+		"http://example.com": {
+			URL:           "http://example.com",
+			TotalRequests: 41,
+			SuccessCount:  40,
+			MinDuration:   time.Hour,
+			StatusCodes:   map[int]int{200: 40, 500: 1},
+		},
+		"http://removed.com": {URL: "http://removed.com", StatusCodes: map[int]int{}},
+	}
+
+	m := NewMonitor(&http.Client{Transport: transport}, []string{"http://example.com"}, WithInitialStats(restored))
+	statsChan, unsubscribe := m.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Start(ctx)
+	}()
+
+	stats := <-statsChan
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.NotContains(t, stats, "http://removed.com")
+	assert.Equal(t, 42, stats["http://example.com"].TotalRequests)
+	assert.Equal(t, 41, stats["http://example.com"].SuccessCount)
+	assert.Equal(t, 41, stats["http://example.com"].StatusCodes[200])
+}
+
+// runProbe runs one probe of url through its scheduled job, waiting for the
+// host limiter the way the scheduler does.
+func runProbe(m *httpMonitor, url string) time.Duration {
+	job := m.job(url, 0)
+	for {
+		wait, ready := job.Admit()
+		switch {
+		case ready != nil:
+			<-ready
+		case wait > 0:
+			time.Sleep(wait)
+		default:
+			return job.Run(context.Background(), 0)
+		}
+	}
+}
+
+func TestHTTPMonitor_jobHostLimiter(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a probe delayed by its host's concurrency limit
+	// Verifies that the wait is recorded as limiter delay and not as request duration
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://example.com/a", httpmock.NewStringResponder(200, "ok"))
+
+	l := limiter.New(limiter.Limits{MaxConcurrent: 1}, nil)
+	release, _, err := l.Acquire(context.Background(), "example.com")
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		release()
+	}()
+
+	m := NewMonitor(&http.Client{Transport: transport}, []string{"http://example.com/a"}, WithHostLimiter(l)).(*httpMonitor)
+
+	runProbe(m, "http://example.com/a")
+
+	stats := m.GetStats()["http://example.com/a"]
+	assert.Equal(t, 1, stats.TotalRequests)
+	assert.GreaterOrEqual(t, stats.TotalLimiterDelay, 25*time.Millisecond)
+	assert.Less(t, stats.TotalDuration, 25*time.Millisecond)
+}
+
+func TestHTTPMonitor_StartThrottledHostDoesNotStarveWorkers(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a host at its concurrency limit sharing a single worker with another host
+	// Verifies that the throttled probe waits in the scheduler and the other host keeps being probed
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://slow.example.com/a", httpmock.NewStringResponder(200, "ok"))
+	transport.RegisterResponder("GET", "http://fast.example.com/a", httpmock.NewStringResponder(200, "ok"))
+
+	l := limiter.New(limiter.Limits{}, map[string]limiter.Limits{"slow.example.com": {MaxConcurrent: 1}})
+	release, _, err := l.Acquire(context.Background(), "slow.example.com")
+	require.NoError(t, err)
+
+	m := NewMonitor(&http.Client{Transport: transport}, []string{"http://slow.example.com/a", "http://fast.example.com/a"},
+		WithHostLimiter(l),
+		WithWorkers(1),
+		WithInterval(5*time.Millisecond),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(60 * time.Millisecond)
+		release()
+	}()
+	require.NoError(t, m.Start(ctx))
+
+	stats := m.GetStats()
+	assert.GreaterOrEqual(t, stats["http://fast.example.com/a"].TotalRequests, 5)
+	assert.Less(t, stats["http://fast.example.com/a"].MaxSchedulingLag, 30*time.Millisecond)
+	slow := stats["http://slow.example.com/a"]
+	require.Positive(t, slow.TotalRequests)
+	assert.GreaterOrEqual(t, slow.MaxLimiterDelay, 50*time.Millisecond)
+}
+
+func TestHTTPMonitor_jobRetry(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a target that recovers on the second attempt
+	// Verifies that the result records the attempts and only the eventual success rate counts it
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://example.com", httpmock.ResponderFromMultipleResponses([]*http.Response{
+		httpmock.NewStringResponse(503, "busy"),
+		httpmock.NewStringResponse(200, "ok"),
+		httpmock.NewStringResponse(200, "ok"),
+	}))
+
+	policy, err := retry.New(config.RetryPolicy{MaxAttempts: 3, RetryOn: []string{"5xx"}, InitialBackoff: config.Duration(time.Millisecond)})
+	assert.NoError(t, err)
+
+	var results []schema.RequestResult
+	m := NewMonitor(&http.Client{Transport: transport}, []string{"http://example.com"},
+		WithRetryPolicies(map[string]retry.Policy{"http://example.com": policy}),
+		WithResultHandler(func(r schema.RequestResult) { results = append(results, r) }),
+	).(*httpMonitor)
+
+	runProbe(m, "http://example.com")
+	runProbe(m, "http://example.com")
+
+	assert.Equal(t, 2, results[0].Attempts)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, 1, results[1].Attempts)
+
+	stats := m.GetStats()["http://example.com"]
+	assert.Equal(t, 2, stats.TotalRequests)
+	assert.Equal(t, 3, stats.TotalAttempts)
+	assert.Equal(t, 100, stats.SuccessPercentage())
+	assert.Equal(t, 50, stats.FirstAttemptSuccessPercentage())
+}
+
+func TestHTTPMonitor_StartNoGoroutineLeak(t *testing.T) {
+	// Not parallel: it counts the goroutines of the whole test binary.
+
+	// Test case for a monitor whose subscriber stops reading
+	// Verifies that frequent probes do not pile up goroutines and none remain after shutdown
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://example.com", httpmock.NewStringResponder(200, "ok"))
+
+	before := runtime.NumGoroutine()
+
+	m := NewMonitor(&http.Client{Transport: transport}, []string{"http://example.com"}, WithInterval(time.Millisecond))
+	statsChan, unsubscribe := m.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Start(ctx)
+	}()
+
+	<-statsChan
+	time.Sleep(50 * time.Millisecond)
+	assert.Greater(t, m.GetStats()["http://example.com"].TotalRequests, 10)
+	cancel()
+	assert.NoError(t, <-done)
+
+	// The subscription is closed after the last pending snapshot.
+	for range statsChan {
+	}
+
+	// Polled by hand: assert.Eventually runs goroutines of its own.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestHTTPMonitor_StopCancelsInFlightProbes(t *testing.T) {
+	t.Parallel()
+
+	// Test case for stopping while a probe waits on a hanging server
+	// Verifies that Stop returns well before the request timeout and the cancelled probe is not recorded
+	requested := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requested)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var handled int32
+	m := NewMonitor(server.Client(), []string{server.URL}, WithResultHandler(func(schema.RequestResult) {
+		atomic.AddInt32(&handled, 1)
+	}))
+
+	done := make(chan error)
+	go func() {
+		done <- m.Start(context.Background())
+	}()
+	<-requested
+
+	start := time.Now()
+	assert.NoError(t, m.Stop())
+	assert.NoError(t, <-done)
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.Zero(t, atomic.LoadInt32(&handled))
+	assert.Zero(t, m.GetStats()[server.URL].TotalRequests)
+}
+
+func TestHTTPMonitor_StartTwice(t *testing.T) {
+	t.Parallel()
+
+	// Test case for Stop before Start and for a second Start
+	// Verifies that Stop is a no-op before Start and a monitor runs only once
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://example.com", httpmock.NewStringResponder(200, "ok"))
+	m := NewMonitor(&http.Client{Transport: transport}, []string{"http://example.com"})
+
+	assert.NoError(t, m.Stop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(t, m.Start(ctx))
+	assert.Error(t, m.Start(context.Background()))
+	assert.NoError(t, m.Stop())
+}
+
+type stubProbe struct {
+	targets []probe.Target
+}
+
+func (p *stubProbe) Run(ctx context.Context, target probe.Target) schema.RequestResult {
+	p.targets = append(p.targets, target)
+	return schema.RequestResult{Timestamp: time.Now(), URL: target.URL, Success: true}
+}
+
+func TestHTTPMonitor_jobDispatchesByCheckType(t *testing.T) {
+	t.Parallel()
+
+	// Test case for targets of different check types
+	// Verifies that each target is run by the probe registered for its check type
+	// and that targets without one fail
+	stub := &stubProbe{}
+	probes := probe.NewRegistry()
+	probes.Register("stub", stub)
+
+	urls := []string{"stub://a", "http://b.example.com", "other://c"}
+	var results []schema.RequestResult
+	m := NewMonitor(http.DefaultClient, urls,
+		WithProbes(probes),
+		WithCheckTypes(map[string]string{"http://b.example.com": "stub"}),
+		WithResultHandler(func(r schema.RequestResult) { results = append(results, r) }),
+	).(*httpMonitor)
+
+	for _, url := range urls {
+		runProbe(m, url)
+	}
+	runProbe(m, "stub://a")
+
+	assert.Len(t, stub.targets, 3)
+	assert.Equal(t, "http://b.example.com", stub.targets[1].URL)
+	assert.Equal(t, 1, stub.targets[2].Sequence)
+	assert.Equal(t, "stub", results[1].CheckType)
+	assert.True(t, results[1].Success)
+	assert.Equal(t, "other", results[2].CheckType)
+	assert.False(t, results[2].Success)
+	assert.Error(t, results[2].Error)
+}
+
+type endlessBody struct{}
+
+func (endlessBody) Read(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (endlessBody) Close() error { return nil }
+
+type endlessTransport struct{}
+
+func (endlessTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: 200, Body: endlessBody{}, Header: make(http.Header), Request: req}, nil
+}
+
+func TestHTTPMonitor_jobBodyTooLarge(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a target streaming an endless body
+	// Verifies that oversized bodies are counted in the target's stats
+	url := "http://download.example.com"
+	probes := probe.NewRegistry()
+	probes.Register(probe.CheckHTTP, probe.NewHTTP(&http.Client{Transport: endlessTransport{}},
+		probe.WithBodyPolicies(map[string]probe.BodyPolicy{url: {MaxSize: 1 << 20}}),
+	))
+	m := NewMonitor(http.DefaultClient, []string{url}, WithProbes(probes)).(*httpMonitor)
+
+	runProbe(m, url)
+
+	stats := m.GetStats()[url]
+	assert.Equal(t, 1, stats.BodyTooLargeCount)
+	assert.Equal(t, 0, stats.SuccessCount)
+}
+
+func TestHostOf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		// Test case for an http target
+		// Verifies that limits are keyed by host and port
+		{
+			name: "http",
+			url:  "http://example.com:8080/health",
+			want: "example.com:8080",
+		},
+		// Test case for targets on different unix sockets
+		// Verifies that each socket gets its own limits instead of sharing an empty host
+		{
+			name: "unix socket",
+			url:  "unix:///run/app.sock:/healthz",
+			want: "unix:/run/app.sock",
+		},
+		{
+			name: "other unix socket",
+			url:  "unix:///run/db.sock",
+			want: "unix:/run/db.sock",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, hostOf(tt.url))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// IntervalPolicy adapts the time between probes of a URL to its recent
+// results. A failing URL is probed every Min to notice its recovery early;
+// once it has succeeded StableAfter times in a row the interval doubles on
+// every probe up to Max. In between, the URL is probed every Base. Zero
+// bounds fall back to Base, which keeps the interval fixed.
+type IntervalPolicy struct {
+	Base        time.Duration
+	Min         time.Duration
+	Max         time.Duration
+	StableAfter int
+}
+
+const defaultStableAfter = 3
+
+// Next returns the interval until the probe following the one just folded
+// into stats.
+func (p IntervalPolicy) Next(stats *schema.URLStats) time.Duration {
+	lower, upper := p.Min, p.Max
+	if lower <= 0 || lower > p.Base {
+		lower = p.Base
+	}
+	if upper < p.Base {
+		upper = p.Base
+	}
+
+	if stats.ConsecutiveFailures > 0 {
+		return lower
+	}
+
+	stableAfter := p.StableAfter
+	if stableAfter <= 0 {
+		stableAfter = defaultStableAfter
+	}
+	if stats.ConsecutiveSuccesses < stableAfter {
+		return p.Base
+	}
+	next := max(stats.EffectiveInterval, p.Base) * 2
+	if next > upper {
+		next = upper
+	}
+	return next
+}
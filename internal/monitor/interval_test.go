@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalPolicy_Next(t *testing.T) {
+	t.Parallel()
+
+	policy := IntervalPolicy{Base: 30 * time.Second, Min: 5 * time.Second, Max: 2 * time.Minute, StableAfter: 2}
+
+	tests := []struct {
+		name  string
+		stats schema.URLStats
+		want  time.Duration
+	}{
+		// Test case for a failing target
+		// Verifies that it is probed at the minimum interval
+		{
+			name:  "failing",
+			stats: schema.URLStats{ConsecutiveFailures: 1, EffectiveInterval: 30 * time.Second},
+			want:  5 * time.Second,
+		},
+		// Test case for a target that just recovered
+		// Verifies that it returns to the base interval before backing off
+		{
+			name:  "recovering",
+			stats: schema.URLStats{ConsecutiveSuccesses: 1, EffectiveInterval: 5 * time.Second},
+			want:  30 * time.Second,
+		},
+		// Test case for a consistently healthy target
+		// Verifies that the interval doubles once the target is stable
+		{
+			name:  "stable",
+			stats: schema.URLStats{ConsecutiveSuccesses: 2, EffectiveInterval: 30 * time.Second},
+			want:  time.Minute,
+		},
+		// Test case for a target already near the upper bound
+		// Verifies that the interval never exceeds the maximum
+		{
+			name:  "capped",
+			stats: schema.URLStats{ConsecutiveSuccesses: 10, EffectiveInterval: 90 * time.Second},
+			want:  2 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Next(&tt.stats))
+		})
+	}
+
+	// Test case for a policy without bounds
+	// Verifies that the interval stays fixed at the base
+	fixed := IntervalPolicy{Base: 5 * time.Second}
+	assert.Equal(t, 5*time.Second, fixed.Next(&schema.URLStats{ConsecutiveFailures: 3}))
+	assert.Equal(t, 5*time.Second, fixed.Next(&schema.URLStats{ConsecutiveSuccesses: 10}))
+}
+
+func TestHTTPMonitor_jobAdaptiveInterval(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a target that fails and then recovers
+	// Verifies that a probe returns the effective interval and exposes it in the stats
+	transport := httpmock.NewMockTransport()
+	transport.RegisterResponder("GET", "http://example.com", httpmock.ResponderFromMultipleResponses([]*http.Response{
+		httpmock.NewStringResponse(503, "down"),
+		httpmock.NewStringResponse(200, "ok"),
+	}))
+
+	m := NewMonitor(&http.Client{Transport: transport}, []string{"http://example.com"},
+		WithInterval(10*time.Second),
+		WithIntervalPolicies(map[string]IntervalPolicy{"http://example.com": {Min: time.Second, Max: time.Minute}}),
+	).(*httpMonitor)
+
+	assert.Equal(t, time.Second, runProbe(m, "http://example.com"))
+	assert.Equal(t, time.Second, m.GetStats()["http://example.com"].EffectiveInterval)
+
+	assert.Equal(t, 10*time.Second, runProbe(m, "http://example.com"))
+	assert.Equal(t, 10*time.Second, m.GetStats()["http://example.com"].EffectiveInterval)
+}
@@ -11,5 +11,13 @@ type Monitor interface {
 
 	Stop() error
 
+	// GetStats returns the stats of every URL. The returned stats are shared
+	// with other readers and must not be modified.
 	GetStats() map[string]*schema.URLStats
+
+	// GetStatsSince returns only the stats of URLs updated after version,
+	// along with the version to pass next time. Version 0 returns every URL.
+	GetStatsSince(version uint64) (map[string]*schema.URLStats, uint64)
+
+	Subscribe() (<-chan map[string]*schema.URLStats, func())
 }
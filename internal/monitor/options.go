@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/limiter"
+	"github.com/dvdk01/http-status-monitor/internal/maintenance"
+	"github.com/dvdk01/http-status-monitor/internal/probe"
+	"github.com/dvdk01/http-status-monitor/internal/retry"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+type Option func(*httpMonitor)
+
+func WithMaintenance(schedule *maintenance.Schedule) Option {
+	return func(m *httpMonitor) {
+		m.maintenance = schedule
+	}
+}
+
+// WithDependencies declares, per URL, the upstream URLs it depends on.
+// Failures of a URL while one of its upstreams is down are tagged as such.
+func WithDependencies(dependencies map[string][]string) Option {
+	return func(m *httpMonitor) {
+		m.dependencies = dependencies
+	}
+}
+
+// WithInitialStats resumes counting from previously saved stats. Entries for
+// URLs that are no longer monitored are ignored.
+func WithInitialStats(stats map[string]*schema.URLStats) Option {
+	return func(m *httpMonitor) {
+		m.initial = stats
+	}
+}
+
+// WithResultHandler registers a callback invoked after every probe, once the
+// result has been folded into the stats. Handlers run in registration order.
+func WithResultHandler(handler func(schema.RequestResult)) Option {
+	return func(m *httpMonitor) {
+		m.onResult = append(m.onResult, handler)
+	}
+}
+
+// DefaultWorkers bounds the number of probes in flight at the same time.
+const DefaultWorkers = 100
+
+func WithWorkers(workers int) Option {
+	return func(m *httpMonitor) {
+		m.workers = workers
+	}
+}
+
+// WithStagger spreads the first probe of every URL evenly over the given
+// window instead of firing them all at once.
+func WithStagger(stagger time.Duration) Option {
+	return func(m *httpMonitor) {
+		m.stagger = stagger
+	}
+}
+
+// WithJitter randomly shifts every following probe by up to +/- jitter.
+func WithJitter(jitter time.Duration) Option {
+	return func(m *httpMonitor) {
+		m.jitter = jitter
+	}
+}
+
+// WithHostLimiter makes every probe wait for its URL host's concurrency and
+// rate limits. The wait is recorded apart from the request duration.
+func WithHostLimiter(l *limiter.HostLimiter) Option {
+	return func(m *httpMonitor) {
+		m.limiter = l
+	}
+}
+
+// WithRetryPolicies sets, per URL, how failed probes are retried before the
+// result is recorded. URLs without a policy are probed once.
+func WithRetryPolicies(policies map[string]retry.Policy) Option {
+	return func(m *httpMonitor) {
+		m.retries = policies
+	}
+}
+
+// WithProbes sets the probes run for each check type. By default only http
+// targets are probed, with the monitor's client.
+func WithProbes(probes *probe.Registry) Option {
+	return func(m *httpMonitor) {
+		m.probes = probes
+	}
+}
+
+// WithCheckTypes sets, per URL, the check type used to probe it. URLs without
+// one are probed according to their scheme.
+func WithCheckTypes(checks map[string]string) Option {
+	return func(m *httpMonitor) {
+		m.checks = checks
+	}
+}
+
+// WithInterval sets the time between probes of URLs without an interval
+// policy of their own.
+func WithInterval(interval time.Duration) Option {
+	return func(m *httpMonitor) {
+		m.interval = interval
+	}
+}
+
+// WithIntervalPolicies sets, per URL, how the time between probes adapts to
+// recent results. Policies without a Base use the default interval.
+func WithIntervalPolicies(policies map[string]IntervalPolicy) Option {
+	return func(m *httpMonitor) {
+		m.intervals = policies
+	}
+}
+
+// WithRenderInterval publishes stats to subscribers at most once per
+// interval. Probes finishing in between are folded into the next snapshot.
+func WithRenderInterval(interval time.Duration) Option {
+	return func(m *httpMonitor) {
+		m.renderInterval = interval
+	}
+}
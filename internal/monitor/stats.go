@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// statsStore keeps the stats of every URL behind a lock of its own, so probes
+// of different URLs never contend. The set of URLs is fixed when the store is
+// created, which lets the map itself be read without locking.
+//
+// Every update bumps a store wide version. Readers get copies that are only
+// remade for URLs updated since the previous read, and can ask for the URLs
+// updated since a version they have already seen.
+type statsStore struct {
+	targets map[string]*targetStats
+	version atomic.Uint64
+}
+
+type targetStats struct {
+	mutex sync.Mutex
+	stats schema.URLStats
+	// changed is the store version of the last update.
+	changed uint64
+	// copied is handed out to readers and never modified; nil once stale.
+	copied *schema.URLStats
+}
+
+func newStatsStore(stats map[string]*schema.URLStats) *statsStore {
+	// The initial stats count as the first update, so that version 0 always
+	// selects every URL.
+	s := &statsStore{targets: make(map[string]*targetStats, len(stats))}
+	s.version.Store(1)
+	for url, st := range stats {
+		s.targets[url] = &targetStats{stats: *copyStats(st), changed: 1}
+	}
+	return s
+}
+
+// update applies fn to the stats of url under its lock. Unknown URLs are
+// ignored.
+func (s *statsStore) update(url string, fn func(stats *schema.URLStats)) {
+	t, ok := s.targets[url]
+	if !ok {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	fn(&t.stats)
+	t.changed = s.version.Add(1)
+	t.copied = nil
+}
+
+// read applies fn to the stats of url under its lock and reports whether the
+// URL is known. fn must not keep or modify the stats.
+func (s *statsStore) read(url string, fn func(stats *schema.URLStats)) bool {
+	t, ok := s.targets[url]
+	if !ok {
+		return false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	fn(&t.stats)
+	return true
+}
+
+// snapshot returns the stats of every URL.
+func (s *statsStore) snapshot() map[string]*schema.URLStats {
+	stats, _ := s.since(0)
+	return stats
+}
+
+// since returns the stats of the URLs updated after version, along with the
+// version to pass next time. A URL updated during the call may be returned
+// again by the next call, but no update is ever missed.
+func (s *statsStore) since(version uint64) (map[string]*schema.URLStats, uint64) {
+	current := s.version.Load()
+	stats := make(map[string]*schema.URLStats)
+	for url, t := range s.targets {
+		t.mutex.Lock()
+		if t.changed > version {
+			if t.copied == nil {
+				t.copied = copyStats(&t.stats)
+			}
+			stats[url] = t.copied
+		}
+		t.mutex.Unlock()
+	}
+	return stats, current
+}
+
+func copyStats(stats *schema.URLStats) *schema.URLStats {
+	copied := *stats
+	copied.StatusCodes = make(map[int]int, len(stats.StatusCodes))
+	for code, count := range stats.StatusCodes {
+		copied.StatusCodes[code] = count
+	}
+	if stats.Codes != nil {
+		copied.Codes = make(map[string]int, len(stats.Codes))
+		for code, count := range stats.Codes {
+			copied.Codes[code] = count
+		}
+	}
+	return &copied
+}
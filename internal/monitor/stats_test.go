@@ -0,0 +1,159 @@
+package monitor
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsStore_since(t *testing.T) {
+	t.Parallel()
+
+	// Test case for reading only the URLs updated since a previous read
+	// Verifies that deltas contain exactly the changed URLs and nothing is missed
+	store := newStatsStore(map[string]*schema.URLStats{
+		"http://a.com": {URL: "http://a.com", StatusCodes: map[int]int{}},
+		"http://b.com": {URL: "http://b.com", StatusCodes: map[int]int{}},
+	})
+
+	all, version := store.since(0)
+	assert.Len(t, all, 2)
+
+	delta, version := store.since(version)
+	assert.Empty(t, delta)
+
+	store.update("http://b.com", func(stats *schema.URLStats) { stats.TotalRequests++ })
+	delta, version = store.since(version)
+	assert.Len(t, delta, 1)
+	assert.Equal(t, 1, delta["http://b.com"].TotalRequests)
+
+	delta, _ = store.since(version)
+	assert.Empty(t, delta)
+}
+
+func TestStatsStore_snapshotReusesUnchangedCopies(t *testing.T) {
+	t.Parallel()
+
+	// Test case for repeated reads with a single changed URL
+	// Verifies that only the changed URL is copied again and copies never change afterwards
+	store := newStatsStore(map[string]*schema.URLStats{
+		"http://a.com": {URL: "http://a.com", StatusCodes: map[int]int{}},
+		"http://b.com": {URL: "http://b.com", StatusCodes: map[int]int{}},
+	})
+
+	first := store.snapshot()
+	store.update("http://a.com", func(stats *schema.URLStats) {
+		stats.TotalRequests++
+		stats.StatusCodes[200]++
+	})
+	second := store.snapshot()
+
+	assert.Same(t, first["http://b.com"], second["http://b.com"])
+	assert.NotSame(t, first["http://a.com"], second["http://a.com"])
+	assert.Equal(t, 0, first["http://a.com"].TotalRequests)
+	assert.Empty(t, first["http://a.com"].StatusCodes)
+	assert.Equal(t, map[int]int{200: 1}, second["http://a.com"].StatusCodes)
+}
+
+// legacyStats is the previous storage: one lock for every URL and a full deep
+// copy on every read. It is kept for the benchmarks below.
+type legacyStats struct {
+	mutex sync.RWMutex
+	stats map[string]*schema.URLStats
+}
+
+func (l *legacyStats) update(url string, fn func(stats *schema.URLStats)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	fn(l.stats[url])
+}
+
+func (l *legacyStats) snapshot() map[string]*schema.URLStats {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	stats := make(map[string]*schema.URLStats)
+	for k, v := range l.stats {
+		stats[k] = copyStats(v)
+	}
+	return stats
+}
+
+type benchStats interface {
+	update(url string, fn func(stats *schema.URLStats))
+	snapshot() map[string]*schema.URLStats
+}
+
+func benchTargets(n int) ([]string, map[string]*schema.URLStats) {
+	urls := make([]string, n)
+	stats := make(map[string]*schema.URLStats, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("http://target%d.example.com", i)
+		stats[urls[i]] = &schema.URLStats{URL: urls[i], StatusCodes: map[int]int{200: 1, 500: 1}}
+	}
+	return urls, stats
+}
+
+func benchImplementations(n int) ([]string, map[string]benchStats) {
+	urls, stats := benchTargets(n)
+	_, legacy := benchTargets(n)
+	return urls, map[string]benchStats{
+		"legacy": &legacyStats{stats: legacy},
+		"store":  newStatsStore(stats),
+	}
+}
+
+var benchResult schema.RequestResult = schema.RequestResult{Status: 200, Success: true, Attempts: 1}
+
+// BenchmarkStats_UpdateParallel measures probes of different URLs recording
+// their results at the same time.
+func BenchmarkStats_UpdateParallel(b *testing.B) {
+	urls, impls := benchImplementations(1000)
+	for _, name := range []string{"legacy", "store"} {
+		impl := impls[name]
+		b.Run(name, func(b *testing.B) {
+			var next atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					url := urls[next.Add(1)%int64(len(urls))]
+					impl.update(url, func(stats *schema.URLStats) { stats.Add(benchResult) })
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStats_UpdateAndRead measures a reader taking a full snapshot after
+// every single update, the worst case for the publisher.
+func BenchmarkStats_UpdateAndRead(b *testing.B) {
+	urls, impls := benchImplementations(1000)
+	for _, name := range []string{"legacy", "store"} {
+		impl := impls[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				impl.update(urls[i%len(urls)], func(stats *schema.URLStats) { stats.Add(benchResult) })
+				_ = impl.snapshot()
+			}
+		})
+	}
+}
+
+// BenchmarkStats_Delta measures reading only the URL changed by the last
+// update.
+func BenchmarkStats_Delta(b *testing.B) {
+	urls, stats := benchTargets(1000)
+	store := newStatsStore(stats)
+	_, version := store.since(0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.update(urls[i%len(urls)], func(stats *schema.URLStats) { stats.Add(benchResult) })
+		_, version = store.since(version)
+	}
+}
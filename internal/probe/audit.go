@@ -0,0 +1,222 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// CheckTLSAudit is the check type auditing the TLS setup of https:// and
+// tls:// targets. It is never implied by the scheme.
+const CheckTLSAudit = "tls-audit"
+
+var auditVersions = []struct {
+	name    string
+	version uint16
+	weak    bool
+}{
+	{"TLS 1.0", tls.VersionTLS10, true},
+	{"TLS 1.1", tls.VersionTLS11, true},
+	{"TLS 1.2", tls.VersionTLS12, false},
+	{"TLS 1.3", tls.VersionTLS13, false},
+}
+
+// Cipher groups offered to TLS 1.0 to 1.2 handshakes. TLS 1.3 suites cannot
+// be chosen and are all strong.
+const (
+	CipherAEAD = "AEAD"
+	CipherCBC  = "CBC"
+	CipherRSA  = "RSA key exchange"
+	Cipher3DES = "3DES"
+	CipherRC4  = "RC4"
+)
+
+var (
+	cipherGroupOrder = []string{CipherAEAD, CipherCBC, CipherRSA, Cipher3DES, CipherRC4}
+	weakCipherGroups = map[string]bool{CipherRSA: true, Cipher3DES: true, CipherRC4: true}
+)
+
+// cipherGroup sorts a cipher suite into one of the groups above. Suites
+// without forward secrecy count as RSA key exchange unless their cipher is
+// weaker still.
+func cipherGroup(name string) string {
+	switch {
+	case strings.Contains(name, "RC4"):
+		return CipherRC4
+	case strings.Contains(name, "3DES"):
+		return Cipher3DES
+	case strings.HasPrefix(name, "TLS_RSA_"):
+		return CipherRSA
+	case strings.Contains(name, "_CBC_"):
+		return CipherCBC
+	default:
+		return CipherAEAD
+	}
+}
+
+func cipherSuites() (all []uint16, groups map[string][]uint16) {
+	groups = make(map[string][]uint16)
+	for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, suite := range suites {
+			if !supportsPreTLS13(suite) {
+				continue
+			}
+			all = append(all, suite.ID)
+			group := cipherGroup(suite.Name)
+			groups[group] = append(groups[group], suite.ID)
+		}
+	}
+	return all, groups
+}
+
+func supportsPreTLS13(suite *tls.CipherSuite) bool {
+	for _, v := range suite.SupportedVersions {
+		if v <= tls.VersionTLS12 {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSAudit handshakes with every TLS version and cipher group and records
+// what the target accepts, whether it staples an OCSP response and, for
+// https targets, its HSTS header. Probes succeed when any handshake does and
+// are degraded when the target accepts old versions or weak ciphers.
+type TLSAudit struct {
+	dialer net.Dialer
+	client *http.Client
+}
+
+// NewTLSAudit returns an audit reading the HSTS header of https targets with
+// client.
+func NewTLSAudit(client *http.Client) *TLSAudit {
+	return &TLSAudit{client: client}
+}
+
+func (p *TLSAudit) Run(ctx context.Context, target Target) schema.RequestResult {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	u, err := neturl.Parse(target.URL)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	audit := &schema.TLSAudit{}
+	all, groups := cipherSuites()
+	var weak bool
+	var lastErr error
+	for _, v := range auditVersions {
+		state, err := p.handshake(ctx, addr, u.Hostname(), v.version, v.version, all)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		audit.Versions = append(audit.Versions, v.name)
+		audit.OCSPStapled = audit.OCSPStapled || len(state.OCSPResponse) > 0
+		if v.weak {
+			weak = true
+			audit.Findings = append(audit.Findings, "accepts "+v.name)
+		}
+	}
+	if len(audit.Versions) == 0 {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Errorf("no TLS version accepted: %w", lastErr)
+		return result
+	}
+
+	var weakGroups []string
+	for _, group := range cipherGroupOrder {
+		if len(groups[group]) == 0 {
+			continue
+		}
+		if _, err := p.handshake(ctx, addr, u.Hostname(), tls.VersionTLS10, tls.VersionTLS12, groups[group]); err != nil {
+			continue
+		}
+		audit.CipherGroups = append(audit.CipherGroups, group)
+		if weakCipherGroups[group] {
+			weakGroups = append(weakGroups, group)
+		}
+	}
+	if len(weakGroups) > 0 {
+		weak = true
+		audit.Findings = append(audit.Findings, "accepts weak ciphers: "+strings.Join(weakGroups, ", "))
+	}
+	if !audit.OCSPStapled {
+		audit.Findings = append(audit.Findings, "no OCSP stapling")
+	}
+
+	if u.Scheme == "https" {
+		// A failed HSTS request is one more finding; it does not void what
+		// the handshakes found.
+		hsts, err := p.hsts(ctx, target.URL)
+		switch {
+		case err != nil:
+			audit.Findings = append(audit.Findings, "HSTS check failed: "+err.Error())
+		case hsts == "":
+			audit.Findings = append(audit.Findings, "no HSTS")
+		}
+		audit.HSTS = hsts
+	}
+
+	result.Duration = time.Since(start)
+	result.Success = true
+	result.Degraded = weak
+	result.TLSAudit = audit
+	return result
+}
+
+func (p *TLSAudit) handshake(ctx context.Context, addr, host string, minVersion, maxVersion uint16, suites []uint16) (tls.ConnectionState, error) {
+	dialer := tls.Dialer{
+		NetDialer: &p.dialer,
+		// The audit is about what the server accepts, not whom it claims to
+		// be; certificates are checked by https and tls probes.
+		Config: &tls.Config{ //nolint:gosec
+			ServerName:         host,
+			InsecureSkipVerify: true,
+			MinVersion:         minVersion,
+			MaxVersion:         maxVersion,
+			CipherSuites:       suites,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close() //nolint
+	return conn.(*tls.Conn).ConnectionState(), nil
+}
+
+func (p *TLSAudit) hsts(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close() //nolint
+	return resp.Header.Get("Strict-Transport-Security"), nil
+}
@@ -0,0 +1,136 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSAudit_Run(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	modernCert := ca.issue(t, time.Now().Add(90*24*time.Hour))
+	modernCert.OCSPStaple = []byte("stapled response")
+
+	tests := []struct {
+		name         string
+		config       *tls.Config
+		hsts         string
+		untrusted    bool
+		wantVersions []string
+		wantGroups   []string
+		wantFindings []string
+		wantDegraded bool
+		wantOCSP     bool
+	}{
+		// Test case for a server still accepting legacy protocols and ciphers
+		// Verifies that old versions and weak cipher groups are reported and degrade the probe
+		{
+			name: "legacy",
+			config: &tls.Config{
+				Certificates: []tls.Certificate{ca.issueRSA(t, time.Now().Add(90*24*time.Hour))},
+				MinVersion:   tls.VersionTLS10,
+				CipherSuites: []uint16{
+					tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+					tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+					tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+					tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+				},
+			},
+			wantVersions: []string{"TLS 1.0", "TLS 1.1", "TLS 1.2", "TLS 1.3"},
+			wantGroups:   []string{CipherAEAD, CipherCBC, CipherRSA, Cipher3DES},
+			wantFindings: []string{
+				"accepts TLS 1.0", "accepts TLS 1.1",
+				"accepts weak ciphers: RSA key exchange, 3DES",
+				"no OCSP stapling", "no HSTS",
+			},
+			wantDegraded: true,
+		},
+		// Test case for a server limited to TLS 1.2 and later with stapling and HSTS
+		// Verifies that a modern setup yields no findings
+		{
+			name: "modern",
+			config: &tls.Config{
+				Certificates: []tls.Certificate{modernCert},
+				MinVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			},
+			hsts:         "max-age=31536000",
+			wantVersions: []string{"TLS 1.2", "TLS 1.3"},
+			wantGroups:   []string{CipherAEAD},
+			wantOCSP:     true,
+		},
+		// Test case for an HSTS request that fails
+		// Verifies that the failure is a finding and the handshake results are kept
+		{
+			name: "hsts check failed",
+			config: &tls.Config{
+				Certificates: []tls.Certificate{modernCert},
+				MinVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			},
+			hsts:         "max-age=31536000",
+			untrusted:    true,
+			wantVersions: []string{"TLS 1.2", "TLS 1.3"},
+			wantGroups:   []string{CipherAEAD},
+			wantFindings: []string{"HSTS check failed: "},
+			wantOCSP:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.hsts != "" {
+					w.Header().Set("Strict-Transport-Security", tt.hsts)
+				}
+			}))
+			server.TLS = tt.config
+			server.Config.ErrorLog = discardLogger()
+			server.StartTLS()
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.pool}}}
+			if tt.untrusted {
+				client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: x509.NewCertPool()}}}
+			}
+			result := NewTLSAudit(client).Run(context.Background(), Target{URL: server.URL, Timeout: 5 * time.Second})
+
+			require.True(t, result.Success, "error: %v", result.Error)
+			require.NotNil(t, result.TLSAudit)
+			assert.Equal(t, tt.wantVersions, result.TLSAudit.Versions)
+			assert.Equal(t, tt.wantGroups, result.TLSAudit.CipherGroups)
+			require.Len(t, result.TLSAudit.Findings, len(tt.wantFindings), "findings: %v", result.TLSAudit.Findings)
+			for i, want := range tt.wantFindings {
+				assert.True(t, strings.HasPrefix(result.TLSAudit.Findings[i], want), "got %q, want %q", result.TLSAudit.Findings[i], want)
+			}
+			assert.Equal(t, tt.wantDegraded, result.Degraded)
+			if !tt.untrusted {
+				assert.Equal(t, tt.hsts, result.TLSAudit.HSTS)
+			}
+			assert.Equal(t, tt.wantOCSP, result.TLSAudit.OCSPStapled)
+		})
+	}
+}
+
+func TestCipherGroup(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, CipherAEAD, cipherGroup(tls.CipherSuiteName(tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256)))
+	assert.Equal(t, CipherCBC, cipherGroup(tls.CipherSuiteName(tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA)))
+	assert.Equal(t, CipherRSA, cipherGroup(tls.CipherSuiteName(tls.TLS_RSA_WITH_AES_128_GCM_SHA256)))
+	assert.Equal(t, Cipher3DES, cipherGroup(tls.CipherSuiteName(tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA)))
+	assert.Equal(t, CipherRC4, cipherGroup(tls.CipherSuiteName(tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA)))
+}
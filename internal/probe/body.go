@@ -0,0 +1,95 @@
+package probe
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+)
+
+// DefaultPrefixSize is how much of a body content assertions see when the
+// target does not say otherwise.
+const DefaultPrefixSize = 64 << 10
+
+var (
+	ErrBodyTooLarge = errors.New("response body too large")
+	ErrBodyMismatch = errors.New("response body does not match")
+)
+
+// BodyPolicy bounds how much of a response body is read and what it must
+// contain. Bodies are streamed and counted; only the prefix checked by the
+// assertions is kept in memory. The zero BodyPolicy reads bodies of any size
+// and checks nothing.
+type BodyPolicy struct {
+	MaxSize    int64
+	Contains   string
+	Matches    *regexp.Regexp
+	PrefixSize int
+}
+
+func NewBodyPolicy(maxSize int64, content config.ContentAssertion) (BodyPolicy, error) {
+	p := BodyPolicy{MaxSize: maxSize, Contains: content.Contains, PrefixSize: content.PrefixSize}
+	if content.Matches != "" {
+		re, err := regexp.Compile(content.Matches)
+		if err != nil {
+			return BodyPolicy{}, fmt.Errorf("content pattern: %w", err)
+		}
+		p.Matches = re
+	}
+	return p, nil
+}
+
+// read consumes body and returns the number of bytes read. It fails when the
+// body cannot be read, exceeds MaxSize or does not pass the assertions.
+func (p BodyPolicy) read(body io.Reader) (int64, error) {
+	var prefix *prefixWriter
+	var w io.Writer = io.Discard
+	if p.Contains != "" || p.Matches != nil {
+		size := p.PrefixSize
+		if size <= 0 {
+			size = DefaultPrefixSize
+		}
+		prefix = &prefixWriter{limit: size}
+		w = prefix
+	}
+
+	r := body
+	if p.MaxSize > 0 {
+		// One byte past the limit tells an oversized body from one that
+		// fits exactly.
+		r = io.LimitReader(body, p.MaxSize+1)
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return n, fmt.Errorf("read body: %w", err)
+	}
+	if p.MaxSize > 0 && n > p.MaxSize {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, p.MaxSize)
+	}
+
+	if prefix != nil {
+		if p.Contains != "" && !bytes.Contains(prefix.buf, []byte(p.Contains)) {
+			return n, fmt.Errorf("%w: %q not found in the first %d bytes", ErrBodyMismatch, p.Contains, prefix.limit)
+		}
+		if p.Matches != nil && !p.Matches.Match(prefix.buf) {
+			return n, fmt.Errorf("%w: %q not matched in the first %d bytes", ErrBodyMismatch, p.Matches, prefix.limit)
+		}
+	}
+	return n, nil
+}
+
+// prefixWriter keeps the first limit bytes written to it and drops the rest.
+type prefixWriter struct {
+	limit int
+	buf   []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.buf); room > 0 {
+		w.buf = append(w.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
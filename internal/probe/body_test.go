@@ -0,0 +1,141 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestBodyPolicy_read(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policy   BodyPolicy
+		body     io.Reader
+		wantSize int64
+		wantErr  error
+	}{
+		// Test case for a policy without limits
+		// Verifies that the whole body is counted
+		{
+			name:     "unbounded",
+			body:     strings.NewReader("hello world"),
+			wantSize: 11,
+		},
+		// Test case for a body exactly at the limit
+		// Verifies that it is not flagged as too large
+		{
+			name:     "exact size",
+			policy:   BodyPolicy{MaxSize: 5},
+			body:     strings.NewReader("hello"),
+			wantSize: 5,
+		},
+		// Test case for a body over the limit
+		// Verifies that reading stops one byte past the limit and fails
+		{
+			name:     "too large",
+			policy:   BodyPolicy{MaxSize: 5},
+			body:     strings.NewReader("hello world"),
+			wantSize: 6,
+			wantErr:  ErrBodyTooLarge,
+		},
+		// Test case for a connection dropping mid body
+		// Verifies that the read error is returned with the bytes read so far
+		{
+			name:     "read error",
+			body:     &failingReader{data: "hel"},
+			wantSize: 3,
+			wantErr:  io.ErrUnexpectedEOF,
+		},
+		// Test case for a substring within the checked prefix
+		// Verifies that the assertion passes
+		{
+			name:     "contains",
+			policy:   BodyPolicy{Contains: "ok", PrefixSize: 4},
+			body:     strings.NewReader(`{"ok": true}`),
+			wantSize: 12,
+		},
+		// Test case for a substring beyond the checked prefix
+		// Verifies that assertions only see the prefix
+		{
+			name:     "contains past prefix",
+			policy:   BodyPolicy{Contains: "true", PrefixSize: 4},
+			body:     strings.NewReader(`{"ok": true}`),
+			wantSize: 12,
+			wantErr:  ErrBodyMismatch,
+		},
+		// Test case for a regular expression assertion
+		// Verifies that a non-matching body fails
+		{
+			name:     "matches",
+			policy:   BodyPolicy{Matches: regexp.MustCompile(`"status":\s*"up"`)},
+			body:     strings.NewReader(`{"status": "down"}`),
+			wantSize: 18,
+			wantErr:  ErrBodyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := tt.policy.read(tt.body)
+			assert.Equal(t, tt.wantSize, size)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+type endlessBody struct{}
+
+func (endlessBody) Read(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (endlessBody) Close() error { return nil }
+
+type endlessTransport struct{}
+
+func (endlessTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: 200, Body: endlessBody{}, Header: make(http.Header), Request: req}, nil
+}
+
+func TestHTTP_RunBodyTooLarge(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a target streaming an endless body
+	// Verifies that the probe stops at the max body size and flags the result as a failure
+	url := "http://download.example.com"
+	h := NewHTTP(&http.Client{Transport: endlessTransport{}},
+		WithBodyPolicies(map[string]BodyPolicy{url: {MaxSize: 1 << 20}}),
+	)
+
+	result := h.Run(context.Background(), Target{URL: url})
+
+	assert.True(t, result.BodyTooLarge)
+	assert.False(t, result.Success)
+	assert.Equal(t, 200, result.Status)
+	assert.Equal(t, 1<<20+1, result.PayloadSize)
+}
@@ -0,0 +1,114 @@
+package probe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// Default certificate expiry thresholds.
+const (
+	DefaultCertWarning  = 21 * 24 * time.Hour
+	DefaultCertCritical = 7 * 24 * time.Hour
+)
+
+var (
+	ErrCertExpiring     = errors.New("certificate expires soon")
+	ErrCertExpired      = errors.New("certificate expired")
+	ErrHostnameMismatch = errors.New("certificate does not match host name")
+)
+
+// CertPolicy sets how close to expiry a certificate may get before probes
+// are degraded (Warning) or fail (Critical). The zero CertPolicy uses the
+// default thresholds.
+type CertPolicy struct {
+	Warning  time.Duration
+	Critical time.Duration
+}
+
+func NewCertPolicy(check config.CertCheck) CertPolicy {
+	return CertPolicy{
+		Warning:  time.Duration(check.WarningDays) * 24 * time.Hour,
+		Critical: time.Duration(check.CriticalDays) * 24 * time.Hour,
+	}
+}
+
+// apply sets the certificate's state and degrades or fails result
+// accordingly.
+func (p CertPolicy) apply(result *schema.RequestResult, cert *schema.CertInfo, now time.Time) {
+	warning, critical := p.Warning, p.Critical
+	if warning <= 0 {
+		warning = DefaultCertWarning
+	}
+	if critical <= 0 {
+		critical = DefaultCertCritical
+	}
+
+	left := cert.ExpiresIn(now)
+	var err error
+	switch {
+	case left <= 0:
+		cert.State = schema.CertExpired
+		err = ErrCertExpired
+	case left <= critical:
+		cert.State = schema.CertCritical
+		err = fmt.Errorf("%w: %s left", ErrCertExpiring, left.Round(time.Hour))
+	case left <= warning:
+		cert.State = schema.CertWarning
+		result.Degraded = result.Success
+	default:
+		cert.State = schema.CertOK
+	}
+	result.Cert = cert
+
+	if err != nil && result.Success {
+		result.Success = false
+		result.Error = err
+	}
+}
+
+// newCertInfo describes the leaf certificate of state for host. The chain is
+// left for the caller to judge.
+func newCertInfo(state tls.ConnectionState, host string) *schema.CertInfo {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := state.PeerCertificates[0]
+	return &schema.CertInfo{
+		Subject:       leaf.Subject.CommonName,
+		Issuer:        issuerName(leaf),
+		NotAfter:      leaf.NotAfter,
+		DNSNames:      leaf.DNSNames,
+		HostnameMatch: leaf.VerifyHostname(host) == nil,
+	}
+}
+
+func issuerName(cert *x509.Certificate) string {
+	if cert.Issuer.CommonName != "" {
+		return cert.Issuer.CommonName
+	}
+	return cert.Issuer.String()
+}
+
+// verifyChain verifies the peer certificates of state against roots, the
+// system roots when nil, without checking the host name.
+func verifyChain(state tls.ConnectionState, roots *x509.CertPool, now time.Time) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no peer certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
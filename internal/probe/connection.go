@@ -0,0 +1,125 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// ConnectionMode decides whether a probe may use a pooled keep-alive
+// connection or has to pay for DNS, TCP and TLS setup like a first-time user.
+type ConnectionMode string
+
+const (
+	ConnectionReuse     ConnectionMode = "reuse"
+	ConnectionFresh     ConnectionMode = "fresh"
+	ConnectionAlternate ConnectionMode = "alternate"
+)
+
+func ParseConnectionMode(s string) (ConnectionMode, error) {
+	switch mode := ConnectionMode(s); mode {
+	case "":
+		return ConnectionReuse, nil
+	case ConnectionReuse, ConnectionFresh, ConnectionAlternate:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown connection mode %q", s)
+}
+
+// freshClient is a copy of the monitor's client that opens a new connection
+// for every request. Transports other than *http.Transport are kept and the
+// requests are marked to close their connection instead.
+type freshClient struct {
+	once   sync.Once
+	client *http.Client
+}
+
+func (f *freshClient) get(client *http.Client) *http.Client {
+	f.once.Do(func() {
+		fresh := *client
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		if t, ok := transport.(*http.Transport); ok {
+			t = t.Clone()
+			t.DisableKeepAlives = true
+			fresh.Transport = t
+		}
+		f.client = &fresh
+	})
+	return f.client
+}
+
+// clientFor returns the client for a probe and whether it must not reuse a
+// connection. Alternating targets use a fresh connection for every other
+// probe, starting with the first. Targets on unix sockets get a client
+// dialing their socket.
+func (h *HTTP) clientFor(target Target) (*http.Client, bool) {
+	fresh := false
+	switch h.connections[target.URL] {
+	case ConnectionFresh:
+		fresh = true
+	case ConnectionAlternate:
+		fresh = target.Sequence%2 == 0
+	}
+	if socket, _, ok := unixTarget(target.URL); ok {
+		return h.unix.get(h.client, socket, fresh), fresh
+	}
+	if !fresh {
+		return h.client, false
+	}
+	return h.fresh.get(h.client), true
+}
+
+// connTrace records which kind of connection a request got and how long it
+// took to get it, which for new connections covers DNS, TCP and TLS setup.
+type connTrace struct {
+	mutex     sync.Mutex
+	start     time.Time
+	reused    bool
+	got       bool
+	setupTime time.Duration
+}
+
+func (c *connTrace) context(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			c.start = time.Now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			c.got = true
+			c.reused = info.Reused
+			if !c.start.IsZero() {
+				c.setupTime = time.Since(c.start)
+			}
+		},
+	})
+}
+
+// apply copies the traced connection kind into result. GotConn only reports
+// connections that were set up, so failed dials leave it empty, as do
+// transports that do not report connections.
+func (c *connTrace) apply(result *schema.RequestResult) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.got {
+		return
+	}
+	result.Connection = schema.ConnectionNew
+	if c.reused {
+		result.Connection = schema.ConnectionReused
+	} else {
+		result.ConnSetup = c.setupTime
+	}
+}
@@ -0,0 +1,148 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConnectionMode(t *testing.T) {
+	t.Parallel()
+
+	mode, err := ParseConnectionMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, ConnectionReuse, mode)
+
+	mode, err = ParseConnectionMode("alternate")
+	assert.NoError(t, err)
+	assert.Equal(t, ConnectionAlternate, mode)
+
+	_, err = ParseConnectionMode("sometimes")
+	assert.Error(t, err)
+}
+
+func TestHTTP_RunConnectionModes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		mode ConnectionMode
+		want []string
+	}{
+		// Test case for the default keep-alive behaviour
+		// Verifies that only the first probe opens a connection
+		{
+			name: "reuse",
+			mode: ConnectionReuse,
+			want: []string{schema.ConnectionNew, schema.ConnectionReused, schema.ConnectionReused},
+		},
+		// Test case for cold connection probing
+		// Verifies that every probe opens a new connection
+		{
+			name: "fresh",
+			mode: ConnectionFresh,
+			want: []string{schema.ConnectionNew, schema.ConnectionNew, schema.ConnectionNew},
+		},
+		// Test case for alternating between cold and warm probes
+		// Verifies that every other probe may reuse the pooled connection
+		{
+			name: "alternate",
+			mode: ConnectionAlternate,
+			want: []string{schema.ConnectionNew, schema.ConnectionNew, schema.ConnectionNew, schema.ConnectionReused},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok")) //nolint
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{}}
+			defer client.CloseIdleConnections()
+
+			h := NewHTTP(client, WithConnectionModes(map[string]ConnectionMode{server.URL: tt.mode}))
+
+			var got []string
+			stats := schema.NewURLStats(server.URL)
+			for i := range tt.want {
+				result := h.Run(context.Background(), Target{URL: server.URL, Sequence: i})
+				got = append(got, result.Connection)
+				stats.Add(result)
+			}
+			assert.Equal(t, tt.want, got)
+
+			reused := 0
+			for _, c := range tt.want {
+				if c == schema.ConnectionReused {
+					reused++
+				}
+			}
+			assert.Equal(t, reused, stats.ReusedConnections)
+			assert.Equal(t, len(tt.want)-reused, stats.NewConnections)
+			assert.Positive(t, stats.AvgNewConnSetup())
+		})
+	}
+}
+
+func TestRun_RefusedDialIsNoConnection(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close() //nolint
+
+	tests := []struct {
+		name  string
+		probe Probe
+		url   string
+	}{
+		// Test case for an http target refusing connections
+		// Verifies that the failed dial is not reported as a new connection
+		{
+			name:  "http",
+			probe: NewHTTP(&http.Client{Transport: &http.Transport{}}),
+			url:   "http://" + addr,
+		},
+		// Test case for a tls target refusing connections
+		// Verifies that the failed handshake is not reported as a new connection
+		{
+			name:  "tls",
+			probe: NewTLS(),
+			url:   "tls://" + addr,
+		},
+		// Test case for a websocket target refusing connections
+		// Verifies that the failed upgrade is not reported as a new connection
+		{
+			name:  "websocket",
+			probe: NewWebSocket(),
+			url:   "ws://" + addr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := tt.probe.Run(context.Background(), Target{URL: tt.url, Timeout: time.Second})
+			stats := schema.NewURLStats(tt.url)
+			stats.Add(result)
+
+			assert.False(t, result.Success)
+			assert.Empty(t, result.Connection)
+			assert.Zero(t, result.ConnSetup)
+			assert.Zero(t, stats.NewConnections)
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	neturl "net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// CheckDNS is the check type of dns://[resolver]/name?type=A targets.
+const CheckDNS = "dns"
+
+var (
+	ErrNoResolver = errors.New("no resolver configured")
+	ErrNoRecords  = errors.New("no records of the queried type")
+)
+
+// dnsTypes are the record types a dns check can query.
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"TXT":   dnsmessage.TypeTXT,
+	"MX":    dnsmessage.TypeMX,
+}
+
+// DNSPolicy is the resolver used for targets whose URL names none and the
+// records every answer must contain. Names are compared without their
+// trailing dot and MX records are written as "preference host".
+type DNSPolicy struct {
+	Resolver string
+	Expect   []string
+}
+
+func NewDNSPolicy(check config.DNSCheck) DNSPolicy {
+	p := DNSPolicy{Resolver: check.Resolver}
+	for _, value := range check.Expect {
+		p.Expect = append(p.Expect, normalizeRecord(value))
+	}
+	return p
+}
+
+// DNS resolves a name with a single query to the target's resolver, over UDP
+// with a fallback to TCP for truncated answers. A probe succeeds when the
+// resolver answers NOERROR with at least one record of the queried type,
+// including every expected one. Answers that differ from the previous one
+// are flagged as changed.
+type DNS struct {
+	dialer   net.Dialer
+	policies map[string]DNSPolicy
+
+	mutex sync.Mutex
+	last  map[string][]string
+}
+
+type DNSOption func(*DNS)
+
+// WithDNSPolicies sets, per URL, the resolver and the expected records.
+func WithDNSPolicies(policies map[string]DNSPolicy) DNSOption {
+	return func(p *DNS) {
+		p.policies = policies
+	}
+}
+
+func NewDNS(opts ...DNSOption) *DNS {
+	p := &DNS{last: make(map[string][]string)}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *DNS) Run(ctx context.Context, target Target) schema.RequestResult {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	policy := p.policies[target.URL]
+	q, err := parseDNSQuery(target.URL, policy.Resolver)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	response, err := p.exchange(ctx, q)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.PayloadSize = len(response)
+
+	answer, err := q.parse(response)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if answer.RCode == rcodeNames[dnsmessage.RCodeSuccess] {
+		p.compare(target.URL, answer)
+	}
+	result.DNS = answer
+	result.Code = answer.RCode
+
+	result.Error = policy.check(answer)
+	result.Success = result.Error == nil
+	return result
+}
+
+// compare flags answer as changed when its records differ from the previous
+// NOERROR answer for url.
+func (p *DNS) compare(url string, answer *schema.DNSAnswer) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	previous, seen := p.last[url]
+	if seen && !slices.Equal(previous, answer.Records) {
+		answer.Changed = true
+		answer.Previous = previous
+	}
+	p.last[url] = answer.Records
+}
+
+func (p DNSPolicy) check(answer *schema.DNSAnswer) error {
+	if answer.RCode != rcodeNames[dnsmessage.RCodeSuccess] {
+		return fmt.Errorf("dns response code %s", answer.RCode)
+	}
+	if len(answer.Records) == 0 {
+		return ErrNoRecords
+	}
+	for _, want := range p.Expect {
+		if !slices.Contains(answer.Records, want) {
+			return fmt.Errorf("expected record %q not in answer %v", want, answer.Records)
+		}
+	}
+	return nil
+}
+
+type dnsQuery struct {
+	resolver string
+	question dnsmessage.Question
+	id       uint16
+}
+
+func parseDNSQuery(rawURL, defaultResolver string) (dnsQuery, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return dnsQuery{}, err
+	}
+	resolver := u.Host
+	if resolver == "" {
+		resolver = defaultResolver
+	}
+	if resolver == "" {
+		return dnsQuery{}, ErrNoResolver
+	}
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		resolver = net.JoinHostPort(resolver, "53")
+	}
+
+	host := strings.TrimPrefix(u.Path, "/")
+	if host == "" {
+		return dnsQuery{}, errors.New("dns target without a name")
+	}
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return dnsQuery{}, err
+	}
+
+	typeName := strings.ToUpper(u.Query().Get("type"))
+	if typeName == "" {
+		typeName = "A"
+	}
+	qtype, ok := dnsTypes[typeName]
+	if !ok {
+		return dnsQuery{}, fmt.Errorf("unsupported record type %q", typeName)
+	}
+
+	return dnsQuery{
+		resolver: resolver,
+		question: dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
+		id:       uint16(rand.N(1 << 16)),
+	}, nil
+}
+
+func (q dnsQuery) message() ([]byte, error) {
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: q.id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{q.question},
+	}
+	return msg.Pack()
+}
+
+// exchange sends the query over UDP and returns the raw response, repeating
+// the query over TCP when the UDP response was truncated.
+func (p *DNS) exchange(ctx context.Context, q dnsQuery) ([]byte, error) {
+	query, err := q.message()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := p.dialer.DialContext(ctx, "udp", q.resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close() //nolint
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now()) //nolint
+	})
+	defer stop()
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var parser dnsmessage.Parser
+		h, err := parser.Start(buf[:n])
+		if err != nil || h.ID != q.id || !h.Response {
+			// Not the answer to this query; keep waiting for it.
+			continue
+		}
+		if h.Truncated {
+			return p.exchangeTCP(ctx, q.resolver, query)
+		}
+		return buf[:n], nil
+	}
+}
+
+func (p *DNS) exchangeTCP(ctx context.Context, resolver string, query []byte) ([]byte, error) {
+	conn, err := p.dialer.DialContext(ctx, "tcp", resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close() //nolint
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now()) //nolint
+	})
+	defer stop()
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// parse reads the response code and the answer section of response.
+func (q dnsQuery) parse(response []byte) (*schema.DNSAnswer, error) {
+	var parser dnsmessage.Parser
+	h, err := parser.Start(response)
+	if err != nil {
+		return nil, err
+	}
+	if h.ID != q.id {
+		return nil, errors.New("dns response does not match the query")
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+
+	answer := &schema.DNSAnswer{RCode: rcodeName(h.RCode), Records: []string{}}
+	for {
+		rh, err := parser.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		answer.AnswerCount++
+		if rh.Type != q.question.Type {
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value, err := recordValue(&parser, rh.Type)
+		if err != nil {
+			return nil, err
+		}
+		answer.Records = append(answer.Records, value)
+	}
+	sort.Strings(answer.Records)
+	return answer, nil
+}
+
+func recordValue(parser *dnsmessage.Parser, rtype dnsmessage.Type) (string, error) {
+	switch rtype {
+	case dnsmessage.TypeA:
+		r, err := parser.AResource()
+		return net.IP(r.A[:]).String(), err
+	case dnsmessage.TypeAAAA:
+		r, err := parser.AAAAResource()
+		return net.IP(r.AAAA[:]).String(), err
+	case dnsmessage.TypeCNAME:
+		r, err := parser.CNAMEResource()
+		return normalizeRecord(r.CNAME.String()), err
+	case dnsmessage.TypeTXT:
+		r, err := parser.TXTResource()
+		return normalizeRecord(strings.Join(r.TXT, "")), err
+	case dnsmessage.TypeMX:
+		r, err := parser.MXResource()
+		return fmt.Sprintf("%d %s", r.Pref, normalizeRecord(r.MX.String())), err
+	}
+	return "", parser.SkipAnswer()
+}
+
+// normalizeRecord drops the trailing dot of names and writes IP addresses in
+// their canonical form, so that expected records compare equal to answers.
+func normalizeRecord(value string) string {
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.String()
+	}
+	return strings.TrimSuffix(value, ".")
+}
+
+var rcodeNames = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeSuccess:        "NOERROR",
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+func rcodeName(rcode dnsmessage.RCode) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
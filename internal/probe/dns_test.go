@@ -0,0 +1,230 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeResolver answers UDP queries from a table of records, standing in for a
+// real DNS server.
+type fakeResolver struct {
+	addr string
+
+	mutex   sync.Mutex
+	records map[string][]dnsmessage.Resource
+}
+
+func newFakeResolver(t *testing.T) *fakeResolver {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() }) //nolint
+
+	r := &fakeResolver{addr: conn.LocalAddr().String(), records: make(map[string][]dnsmessage.Resource)}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if response, err := r.answer(buf[:n]); err == nil {
+				conn.WriteTo(response, from) //nolint
+			}
+		}
+	}()
+	return r
+}
+
+func (r *fakeResolver) set(name string, records ...dnsmessage.ResourceBody) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	n := dnsmessage.MustNewName(name)
+	resources := make([]dnsmessage.Resource, 0, len(records))
+	for _, body := range records {
+		resources = append(resources, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: n, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   body,
+		})
+	}
+	r.records[name] = resources
+}
+
+func (r *fakeResolver) answer(query []byte) ([]byte, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		return nil, err
+	}
+	msg.Response = true
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	records, ok := r.records[msg.Questions[0].Name.String()]
+	if !ok {
+		msg.RCode = dnsmessage.RCodeNameError
+	}
+	msg.Answers = records
+	return msg.Pack()
+}
+
+func TestDNS_Run(t *testing.T) {
+	t.Parallel()
+
+	resolver := newFakeResolver(t)
+	resolver.set("example.com.",
+		&dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+		&dnsmessage.AResource{A: [4]byte{192, 0, 2, 2}},
+		&dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}},
+		&dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.com.")},
+	)
+	resolver.set("www.example.com.", &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("example.com.")})
+	resolver.set("v6.example.com.", &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}})
+
+	tests := []struct {
+		name        string
+		url         string
+		expect      []string
+		wantRecords []string
+		wantRCode   string
+		wantErr     bool
+	}{
+		// Test case for an A query answered with two addresses
+		// Verifies that records of other types in the answer are counted but not reported
+		{
+			name:        "A records",
+			url:         "dns://" + resolver.addr + "/example.com",
+			expect:      []string{"192.0.2.2"},
+			wantRecords: []string{"192.0.2.1", "192.0.2.2"},
+			wantRCode:   "NOERROR",
+		},
+		// Test case for an AAAA query with the expected address in long form
+		// Verifies that expected addresses are compared in canonical form
+		{
+			name:        "AAAA record",
+			url:         "dns://" + resolver.addr + "/v6.example.com?type=AAAA",
+			expect:      []string{"2001:0db8::0001"},
+			wantRecords: []string{"2001:db8::1"},
+			wantRCode:   "NOERROR",
+		},
+		// Test case for a CNAME query
+		// Verifies that names are compared without their trailing dot
+		{
+			name:        "CNAME record",
+			url:         "dns://" + resolver.addr + "/www.example.com?type=CNAME",
+			expect:      []string{"example.com."},
+			wantRecords: []string{"example.com"},
+			wantRCode:   "NOERROR",
+		},
+		// Test case for a TXT query with a lower case type
+		// Verifies that the record type is case insensitive and strings are joined
+		{
+			name:        "TXT record",
+			url:         "dns://" + resolver.addr + "/example.com?type=txt",
+			expect:      []string{"v=spf1 -all"},
+			wantRecords: []string{"v=spf1 -all"},
+			wantRCode:   "NOERROR",
+		},
+		// Test case for an MX query
+		// Verifies that MX records carry their preference
+		{
+			name:        "MX record",
+			url:         "dns://" + resolver.addr + "/example.com?type=MX",
+			expect:      []string{"10 mail.example.com"},
+			wantRecords: []string{"10 mail.example.com"},
+			wantRCode:   "NOERROR",
+		},
+		// Test case for an answer missing an expected record
+		// Verifies that the probe fails when a record is not where it should be
+		{
+			name:        "unexpected answer",
+			url:         "dns://" + resolver.addr + "/example.com",
+			expect:      []string{"198.51.100.7"},
+			wantRecords: []string{"192.0.2.1", "192.0.2.2"},
+			wantRCode:   "NOERROR",
+			wantErr:     true,
+		},
+		// Test case for a name the resolver does not know
+		// Verifies that NXDOMAIN fails the probe and is reported as the response code
+		{
+			name:        "NXDOMAIN",
+			url:         "dns://" + resolver.addr + "/missing.example.com",
+			wantRecords: []string{},
+			wantRCode:   "NXDOMAIN",
+			wantErr:     true,
+		},
+		// Test case for a query type without records
+		// Verifies that an empty answer fails the probe
+		{
+			name:        "no records",
+			url:         "dns://" + resolver.addr + "/v6.example.com?type=A",
+			wantRecords: []string{},
+			wantRCode:   "NOERROR",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewDNS(WithDNSPolicies(map[string]DNSPolicy{tt.url: NewDNSPolicy(config.DNSCheck{Expect: tt.expect})}))
+			result := p.Run(context.Background(), Target{URL: tt.url, Timeout: time.Second})
+
+			require.NotNil(t, result.DNS, "error: %v", result.Error)
+			assert.Equal(t, tt.wantRCode, result.DNS.RCode)
+			assert.Equal(t, tt.wantRecords, result.DNS.Records)
+			assert.Equal(t, !tt.wantErr, result.Success, "error: %v", result.Error)
+			assert.Positive(t, result.Duration)
+		})
+	}
+}
+
+func TestDNS_RunDetectsAnswerChanges(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a record changing between probes
+	// Verifies that only the probe seeing the new answer is flagged, with the old records
+	resolver := newFakeResolver(t)
+	resolver.set("example.com.", &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}})
+
+	url := "dns:///example.com"
+	p := NewDNS(WithDNSPolicies(map[string]DNSPolicy{url: {Resolver: resolver.addr}}))
+	run := func() bool {
+		result := p.Run(context.Background(), Target{URL: url, Timeout: time.Second})
+		require.True(t, result.Success, "error: %v", result.Error)
+		return result.DNS.Changed
+	}
+
+	assert.False(t, run())
+	assert.False(t, run())
+
+	resolver.set("example.com.", &dnsmessage.AResource{A: [4]byte{203, 0, 113, 9}})
+	result := p.Run(context.Background(), Target{URL: url, Timeout: time.Second})
+	assert.True(t, result.DNS.Changed)
+	assert.Equal(t, []string{"192.0.2.1"}, result.DNS.Previous)
+	assert.Equal(t, []string{"203.0.113.9"}, result.DNS.Records)
+
+	assert.False(t, run())
+}
+
+func TestDNS_RunWithoutResolver(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a dns target naming no resolver
+	// Verifies that the probe fails instead of guessing one
+	result := NewDNS().Run(context.Background(), Target{URL: "dns:///example.com", Timeout: time.Second})
+
+	assert.False(t, result.Success)
+	assert.True(t, errors.Is(result.Error, ErrNoResolver))
+}
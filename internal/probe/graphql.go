@@ -0,0 +1,197 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// CheckGraphQL is the check type POSTing a GraphQL query to an http:// or
+// https:// target. It is never implied by the scheme.
+const CheckGraphQL = "graphql"
+
+// MaxGraphQLResponse bounds how much of a GraphQL response is read.
+const MaxGraphQLResponse = 1 << 20
+
+var (
+	ErrGraphQLErrors   = errors.New("graphql errors")
+	ErrGraphQLMismatch = errors.New("graphql data does not match")
+)
+
+// GraphQLPolicy is the query sent to a target and the values its response
+// data must hold, keyed by dotted path.
+type GraphQLPolicy struct {
+	Query     string
+	Variables map[string]any
+	Expect    map[string]any
+}
+
+func NewGraphQLPolicy(check config.GraphQLCheck) GraphQLPolicy {
+	return GraphQLPolicy{Query: check.Query, Variables: check.Variables, Expect: check.Expect}
+}
+
+// GraphQL POSTs a target's query and succeeds on a 2xx response without
+// errors whose data holds the expected values. Unlike an http probe it reads
+// the response, since GraphQL servers report errors with status 200.
+type GraphQL struct {
+	client   *http.Client
+	policies map[string]GraphQLPolicy
+}
+
+type GraphQLOption func(*GraphQL)
+
+// WithGraphQLPolicies sets, per URL, the query and the expected data.
+func WithGraphQLPolicies(policies map[string]GraphQLPolicy) GraphQLOption {
+	return func(p *GraphQL) {
+		p.policies = policies
+	}
+}
+
+func NewGraphQL(client *http.Client, opts ...GraphQLOption) *GraphQL {
+	p := &GraphQL{client: client}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (p *GraphQL) Run(ctx context.Context, target Target) schema.RequestResult {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	policy := p.policies[target.URL]
+	body, err := json.Marshal(map[string]any{"query": policy.Query, "variables": policy.Variables})
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	var trace connTrace
+	req, err := http.NewRequestWithContext(trace.context(ctx), "POST", target.URL, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	trace.apply(&result)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = err
+		return result
+	}
+	defer resp.Body.Close() //nolint
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxGraphQLResponse+1))
+	result.Duration = time.Since(start)
+	result.Status = resp.StatusCode
+	result.PayloadSize = len(data)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	if len(data) > MaxGraphQLResponse {
+		result.Error = ErrBodyTooLarge
+		result.BodyTooLarge = true
+		return result
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Errorf("graphql status %d", resp.StatusCode)
+		return result
+	}
+
+	result.Error = policy.check(data)
+	result.Success = result.Error == nil
+	return result
+}
+
+// check fails responses carrying errors or missing an expected value.
+func (p GraphQLPolicy) check(body []byte) error {
+	var resp graphQLResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("graphql response: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("%w: %s", ErrGraphQLErrors, strings.Join(messages, "; "))
+	}
+	if len(p.Expect) == 0 {
+		return nil
+	}
+
+	var data any
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return fmt.Errorf("graphql data: %w", err)
+	}
+	paths := make([]string, 0, len(p.Expect))
+	for path := range p.Expect {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		got, ok := lookupPath(data, path)
+		if !ok {
+			return fmt.Errorf("%w: %s missing", ErrGraphQLMismatch, path)
+		}
+		if !reflect.DeepEqual(got, p.Expect[path]) {
+			return fmt.Errorf("%w: %s is %v, want %v", ErrGraphQLMismatch, path, got, p.Expect[path])
+		}
+	}
+	return nil
+}
+
+// lookupPath follows a dotted path through decoded JSON objects and arrays.
+func lookupPath(value any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,102 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQL_Run(t *testing.T) {
+	t.Parallel()
+
+	// The server answers {"id": "1"} with a user and anything else with an
+	// error, always with status 200 like most GraphQL servers.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if r.Method != "POST" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Variables["id"] != "1" {
+			w.Write([]byte(`{"data": {"user": null}, "errors": [{"message": "user not found"}]}`)) //nolint
+			return
+		}
+		w.Write([]byte(`{"data": {"user": {"name": "alice", "age": 42, "roles": ["admin", "dev"]}}}`)) //nolint
+	}))
+	t.Cleanup(server.Close)
+
+	query := "query($id: ID!) { user(id: $id) { name age roles } }"
+	tests := []struct {
+		name        string
+		policy      GraphQLPolicy
+		wantSuccess bool
+		wantErr     error
+	}{
+		// Test case for a query answered without errors
+		// Verifies that expected values are found through objects and arrays
+		{
+			name: "expected data",
+			policy: GraphQLPolicy{
+				Query:     query,
+				Variables: map[string]any{"id": "1"},
+				Expect:    map[string]any{"user.name": "alice", "user.age": float64(42), "user.roles.1": "dev"},
+			},
+			wantSuccess: true,
+		},
+		// Test case for a 200 response carrying errors
+		// Verifies that a non-empty errors array fails the probe
+		{
+			name:    "errors with status 200",
+			policy:  GraphQLPolicy{Query: query, Variables: map[string]any{"id": "2"}},
+			wantErr: ErrGraphQLErrors,
+		},
+		// Test case for data holding a different value
+		// Verifies that data assertions fail the probe
+		{
+			name: "unexpected data",
+			policy: GraphQLPolicy{
+				Query:     query,
+				Variables: map[string]any{"id": "1"},
+				Expect:    map[string]any{"user.name": "bob"},
+			},
+			wantErr: ErrGraphQLMismatch,
+		},
+		// Test case for a path missing from the data
+		// Verifies that missing fields fail the probe
+		{
+			name: "missing field",
+			policy: GraphQLPolicy{
+				Query:     query,
+				Variables: map[string]any{"id": "1"},
+				Expect:    map[string]any{"user.roles.5": "ops"},
+			},
+			wantErr: ErrGraphQLMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewGraphQL(server.Client(), WithGraphQLPolicies(map[string]GraphQLPolicy{server.URL: tt.policy}))
+			result := p.Run(context.Background(), Target{URL: server.URL, Timeout: time.Second})
+
+			require.Equal(t, 200, result.Status)
+			assert.Equal(t, tt.wantSuccess, result.Success, "error: %v", result.Error)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(result.Error, tt.wantErr), "got %v", result.Error)
+			}
+		})
+	}
+}
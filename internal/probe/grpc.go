@@ -0,0 +1,106 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// CheckGRPC is the check type of grpc://host:port[/service] targets.
+const CheckGRPC = "grpc"
+
+// GRPC calls Check of the standard grpc.health.v1.Health service, asking
+// about the service named by the URL path or, without one, the server as a
+// whole. Probes succeed when the answer is SERVING. The returned status, or
+// the RPC error code, is recorded as the result's code.
+type GRPC struct {
+	tlsConfig *tls.Config
+	tls       map[string]bool
+}
+
+type GRPCOption func(*GRPC)
+
+// WithGRPCTLS sets, per URL, whether the health check is made over TLS.
+func WithGRPCTLS(targets map[string]bool) GRPCOption {
+	return func(p *GRPC) {
+		p.tls = targets
+	}
+}
+
+// WithGRPCTLSConfig sets the TLS configuration of health checks made over
+// TLS, which by default verify the server against the system roots.
+func WithGRPCTLSConfig(config *tls.Config) GRPCOption {
+	return func(p *GRPC) {
+		p.tlsConfig = config
+	}
+}
+
+func NewGRPC(opts ...GRPCOption) *GRPC {
+	p := &GRPC{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *GRPC) Run(ctx context.Context, target Target) schema.RequestResult {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	u, err := neturl.Parse(target.URL)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	creds := insecure.NewCredentials()
+	if p.tls[target.URL] {
+		config := &tls.Config{}
+		if p.tlsConfig != nil {
+			config = p.tlsConfig.Clone()
+		}
+		creds = credentials.NewTLS(config)
+	}
+	conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer conn.Close() //nolint
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: strings.TrimPrefix(u.Path, "/"),
+	})
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Code = status.Code(err).String()
+		result.Error = err
+		return result
+	}
+
+	result.Code = resp.GetStatus().String()
+	result.Success = resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	if !result.Success {
+		result.Error = fmt.Errorf("health status %s", result.Code)
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthServer serves the health service in process and returns its address.
+func healthServer(t *testing.T, opts ...grpc.ServerOption) (string, *health.Server) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer(opts...)
+	h := health.NewServer()
+	healthpb.RegisterHealthServer(server, h)
+	go server.Serve(ln) //nolint
+	t.Cleanup(server.Stop)
+	return ln.Addr().String(), h
+}
+
+func TestGRPC_Run(t *testing.T) {
+	t.Parallel()
+
+	addr, h := healthServer(t)
+	h.SetServingStatus("orders.v1.Orders", healthpb.HealthCheckResponse_SERVING)
+	h.SetServingStatus("billing.v1.Billing", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	tests := []struct {
+		name        string
+		url         string
+		wantSuccess bool
+		wantCode    string
+	}{
+		// Test case for the server as a whole
+		// Verifies that an empty service name asks about the server
+		{
+			name:        "server",
+			url:         "grpc://" + addr,
+			wantSuccess: true,
+			wantCode:    "SERVING",
+		},
+		// Test case for a healthy service
+		// Verifies that SERVING is a success
+		{
+			name:        "serving service",
+			url:         "grpc://" + addr + "/orders.v1.Orders",
+			wantSuccess: true,
+			wantCode:    "SERVING",
+		},
+		// Test case for a service reporting itself unhealthy
+		// Verifies that any other status fails the probe and is recorded
+		{
+			name:     "not serving service",
+			url:      "grpc://" + addr + "/billing.v1.Billing",
+			wantCode: "NOT_SERVING",
+		},
+		// Test case for a service the server does not know
+		// Verifies that the RPC error code is recorded
+		{
+			name:     "unknown service",
+			url:      "grpc://" + addr + "/unknown.v1.Unknown",
+			wantCode: "NotFound",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := NewGRPC().Run(context.Background(), Target{URL: tt.url, Timeout: 5 * time.Second})
+
+			assert.Equal(t, tt.wantSuccess, result.Success, "error: %v", result.Error)
+			assert.Equal(t, tt.wantCode, result.Code)
+			assert.Positive(t, result.Duration)
+		})
+	}
+}
+
+func TestGRPC_RunTLS(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a health service behind TLS
+	// Verifies that the check is made over TLS when the target asks for it
+	ca := newTestCA(t)
+	cert := ca.issue(t, time.Now().Add(24*time.Hour))
+	addr, _ := healthServer(t, grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}})))
+	url := "grpc://" + addr
+
+	p := NewGRPC(WithGRPCTLS(map[string]bool{url: true}), WithGRPCTLSConfig(&tls.Config{RootCAs: ca.pool}))
+	result := p.Run(context.Background(), Target{URL: url, Timeout: 5 * time.Second})
+	assert.True(t, result.Success, "error: %v", result.Error)
+
+	result = NewGRPC().Run(context.Background(), Target{URL: url, Timeout: 5 * time.Second})
+	assert.False(t, result.Success)
+}
@@ -0,0 +1,127 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// HTTP checks a target with a GET request. Responses with a 2xx or 3xx status
+// whose body passes the target's body policy succeed. Targets written as
+// unix:///path/app.sock:/healthz are requested over the unix socket.
+type HTTP struct {
+	client      *http.Client
+	bodies      map[string]BodyPolicy
+	connections map[string]ConnectionMode
+	certs       map[string]CertPolicy
+	fresh       freshClient
+	unix        unixClients
+}
+
+type HTTPOption func(*HTTP)
+
+// WithBodyPolicies sets, per URL, the maximum body size and the content
+// assertions applied to responses. URLs without a policy have their bodies
+// counted in full.
+func WithBodyPolicies(policies map[string]BodyPolicy) HTTPOption {
+	return func(h *HTTP) {
+		h.bodies = policies
+	}
+}
+
+// WithConnectionModes sets, per URL, whether probes reuse pooled connections,
+// open a fresh one every time or alternate between both. URLs without a mode
+// reuse connections.
+func WithConnectionModes(modes map[string]ConnectionMode) HTTPOption {
+	return func(h *HTTP) {
+		h.connections = modes
+	}
+}
+
+// WithCertPolicies sets, per URL, the certificate expiry thresholds of https
+// targets. URLs without a policy use the default thresholds.
+func WithCertPolicies(policies map[string]CertPolicy) HTTPOption {
+	return func(h *HTTP) {
+		h.certs = policies
+	}
+}
+
+func NewHTTP(client *http.Client, opts ...HTTPOption) *HTTP {
+	h := &HTTP{client: client}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *HTTP) Run(ctx context.Context, target Target) schema.RequestResult {
+	url := target.URL
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	client, fresh := h.clientFor(target)
+	var trace connTrace
+
+	requestURL := url
+	if _, u, ok := unixTarget(url); ok {
+		requestURL = u
+	}
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(trace.context(ctx), "GET", requestURL, nil)
+	if err != nil {
+		return schema.RequestResult{
+			Timestamp: start,
+			URL:       url,
+			Error:     err,
+			Success:   false,
+		}
+	}
+
+	req.Close = fresh
+
+	resp, err := client.Do(req)
+	duration := time.Since(start)
+
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       url,
+		Duration:  duration,
+	}
+	trace.apply(&result)
+
+	if err != nil {
+		result.Error = err
+		result.Success = false
+		return result
+	}
+	defer resp.Body.Close() //nolint
+
+	result.Status = resp.StatusCode
+	result.Success = resp.StatusCode >= 200 && resp.StatusCode < 400
+
+	size, err := h.bodies[url].read(resp.Body)
+	result.PayloadSize = int(size)
+	if err != nil {
+		result.Error = err
+		result.Success = false
+		result.BodyTooLarge = errors.Is(err, ErrBodyTooLarge)
+	}
+
+	if resp.TLS != nil {
+		if cert := newCertInfo(*resp.TLS, req.URL.Hostname()); cert != nil {
+			// The transport only hands out responses over verified chains,
+			// unless verification is turned off.
+			cert.ChainValid = len(resp.TLS.VerifiedChains) > 0
+			h.certs[url].apply(&result, cert, start)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,118 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP_Run(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		url          string
+		mockResponse *http.Response
+		mockError    error
+		expected     schema.RequestResult
+	}{
+		// Test case for successful HTTP request with 200 status code
+		// Verifies that the probe correctly processes a successful response
+		// and sets the appropriate success flag and status code
+		{
+			name: "successful request",
+			url:  "http://example.com/success",
+			mockResponse: &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader("test response")),
+			},
+			mockError: nil,
+			expected: schema.RequestResult{
+				URL:     "http://example.com/success",
+				Status:  200,
+				Success: true,
+			},
+		},
+		// Test case for failed HTTP request with 404 status code
+		// Verifies that the probe correctly handles a not found response
+		// and sets the success flag to false while preserving the status code
+		{
+			name: "404 response",
+			url:  "http://example.com/notfound",
+			mockResponse: &http.Response{
+				StatusCode: 404,
+				Body:       io.NopCloser(strings.NewReader("not found")),
+			},
+			mockError: nil,
+			expected: schema.RequestResult{
+				URL:     "http://example.com/notfound",
+				Status:  404,
+				Success: false,
+			},
+		},
+		// Test case for network error during HTTP request
+		// Verifies that the probe correctly handles connection errors
+		// and sets appropriate error state and success flag
+		{
+			name:         "request error",
+			url:          "http://error.com",
+			mockResponse: nil,
+			mockError:    errors.New("connection refused"),
+			expected: schema.RequestResult{
+				URL:     "http://error.com",
+				Success: false,
+				Error:   errors.New("connection refused"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Create a new transport for each test
+			transport := httpmock.NewMockTransport()
+			client := &http.Client{Transport: transport}
+			defer transport.Reset()
+
+			// Register responder for this test
+			if tt.mockError != nil {
+				transport.RegisterResponder("GET", tt.url,
+					func(req *http.Request) (*http.Response, error) {
+						return nil, tt.mockError
+					},
+				)
+			} else {
+				transport.RegisterResponder("GET", tt.url,
+					func(req *http.Request) (*http.Response, error) {
+						return tt.mockResponse, nil
+					},
+				)
+			}
+
+			result := NewHTTP(client).Run(context.Background(), Target{URL: tt.url, Timeout: time.Second})
+
+			// Ověření základních vlastností
+			assert.Equal(t, tt.expected.URL, result.URL)
+			if tt.mockError != nil {
+				assert.False(t, result.Success)
+				assert.Equal(t, 0, result.Status)
+				assert.Error(t, result.Error)
+			} else {
+				assert.Equal(t, tt.expected.Success, result.Success)
+				assert.Equal(t, tt.expected.Status, result.Status)
+				assert.NoError(t, result.Error)
+			}
+			assert.NotZero(t, result.Duration)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// CheckHTTP is the check type of http:// and https:// targets.
+const CheckHTTP = "http"
+
+// Target is what a probe needs to know about the target it checks.
+type Target struct {
+	URL     string
+	Check   string
+	Timeout time.Duration
+	// Sequence numbers the probes of a target, starting at 0.
+	Sequence int
+}
+
+// Probe checks a target once. It reports failures in the result rather than
+// as an error, and returns once ctx is done.
+type Probe interface {
+	Run(ctx context.Context, target Target) schema.RequestResult
+}
+
+// Registry maps check types to the probes implementing them.
+type Registry struct {
+	probes map[string]Probe
+}
+
+func NewRegistry() *Registry {
+	return &Registry{probes: make(map[string]Probe)}
+}
+
+func (r *Registry) Register(check string, p Probe) {
+	r.probes[check] = p
+}
+
+func (r *Registry) Lookup(check string) (Probe, bool) {
+	p, ok := r.probes[check]
+	return p, ok
+}
+
+// Checks returns the registered check types in order.
+func (r *Registry) Checks() []string {
+	checks := make([]string, 0, len(r.probes))
+	for check := range r.probes {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	return checks
+}
+
+// Run runs the probe registered for the target's check type. Targets without
+// a registered probe fail.
+func (r *Registry) Run(ctx context.Context, target Target) schema.RequestResult {
+	p, ok := r.Lookup(target.Check)
+	if !ok {
+		return schema.RequestResult{
+			Timestamp: time.Now(),
+			URL:       target.URL,
+			CheckType: target.Check,
+			Error:     fmt.Errorf("no probe for check type %q", target.Check),
+		}
+	}
+	result := p.Run(ctx, target)
+	result.CheckType = target.Check
+	return result
+}
+
+// CheckType derives the check type from the URL scheme; http, https and unix
+// map to CheckHTTP and ws and wss to CheckWebSocket.
+func CheckType(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	switch scheme := strings.ToLower(u.Scheme); scheme {
+	case "http", "https", "unix":
+		return CheckHTTP
+	case "ws", "wss":
+		return CheckWebSocket
+	default:
+		return scheme
+	}
+}
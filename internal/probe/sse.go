@@ -0,0 +1,203 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// CheckSSE is the check type reading Server-Sent Events from an http:// or
+// https:// target. It is never implied by the scheme.
+const CheckSSE = "sse"
+
+// MaxSSELine bounds the length of a line in an event stream.
+const MaxSSELine = 1 << 20
+
+var (
+	ErrNotEventStream  = errors.New("not an event stream")
+	ErrEventsMissing   = errors.New("events missing")
+	ErrHeartbeatMissed = errors.New("heartbeat missed")
+)
+
+// SSEPolicy is what an sse check waits for: Events events, 1 by default,
+// whose data matches Match, within Deadline. A stream silent for longer than
+// Heartbeat fails the check.
+type SSEPolicy struct {
+	Events    int
+	Match     *regexp.Regexp
+	Deadline  time.Duration
+	Heartbeat time.Duration
+}
+
+func NewSSEPolicy(check config.SSECheck) (SSEPolicy, error) {
+	p := SSEPolicy{
+		Events:    check.Events,
+		Deadline:  time.Duration(check.Deadline),
+		Heartbeat: time.Duration(check.Heartbeat),
+	}
+	if check.Match != "" {
+		re, err := regexp.Compile(check.Match)
+		if err != nil {
+			return SSEPolicy{}, fmt.Errorf("match pattern: %w", err)
+		}
+		p.Match = re
+	}
+	return p, nil
+}
+
+// SSE opens an event stream and succeeds once the expected events arrived.
+// It reports the time to the first event and the longest silence on the
+// stream, counting comments as heartbeats.
+type SSE struct {
+	client   *http.Client
+	policies map[string]SSEPolicy
+}
+
+type SSEOption func(*SSE)
+
+// WithSSEPolicies sets, per URL, the events waited for.
+func WithSSEPolicies(policies map[string]SSEPolicy) SSEOption {
+	return func(p *SSE) {
+		p.policies = policies
+	}
+}
+
+func NewSSE(client *http.Client, opts ...SSEOption) *SSE {
+	p := &SSE{client: client}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Run waits up to the policy's deadline, or the target's timeout when the
+// policy sets none.
+func (p *SSE) Run(ctx context.Context, target Target) schema.RequestResult {
+	policy := p.policies[target.URL]
+	deadline := policy.Deadline
+	if deadline <= 0 {
+		deadline = target.Timeout
+	}
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	var trace connTrace
+	req, err := http.NewRequestWithContext(trace.context(ctx), "GET", target.URL, nil)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := p.client.Do(req)
+	trace.apply(&result)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = err
+		return result
+	}
+	defer resp.Body.Close() //nolint
+
+	result.Status = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Errorf("sse status %d", resp.StatusCode)
+		return result
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		result.Duration = time.Since(start)
+		result.Error = fmt.Errorf("%w: content type %q", ErrNotEventStream, resp.Header.Get("Content-Type"))
+		return result
+	}
+
+	result.Error = policy.read(ctx, cancel, resp, start, &result)
+	result.Duration = time.Since(start)
+	result.Success = result.Error == nil
+	return result
+}
+
+// read consumes the stream until enough events arrived. A heartbeat timer,
+// reset on every line, cancels ctx when the stream falls silent.
+func (p SSEPolicy) read(ctx context.Context, cancel context.CancelCauseFunc, resp *http.Response, start time.Time, result *schema.RequestResult) error {
+	want := max(p.Events, 1)
+	var heartbeat *time.Timer
+	if p.Heartbeat > 0 {
+		heartbeat = time.AfterFunc(p.Heartbeat, func() {
+			cancel(fmt.Errorf("%w: silent for more than %v", ErrHeartbeatMissed, p.Heartbeat))
+		})
+		defer heartbeat.Stop()
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, MaxSSELine)
+	matched := 0
+	last := time.Now()
+	var data []string
+	for scanner.Scan() {
+		now := time.Now()
+		result.MaxStreamGap = max(result.MaxStreamGap, now.Sub(last))
+		last = now
+		if heartbeat != nil {
+			heartbeat.Reset(p.Heartbeat)
+		}
+
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		result.PayloadSize += len(scanner.Bytes()) + 1
+		field, value, _ := strings.Cut(line, ":")
+		switch {
+		case line == "":
+			// A blank line dispatches the event, if it carried data.
+			if data == nil {
+				continue
+			}
+			if result.Events == 0 {
+				result.FirstEvent = now.Sub(start)
+			}
+			result.Events++
+			if p.Match == nil || p.Match.MatchString(strings.Join(data, "\n")) {
+				matched++
+			}
+			data = nil
+			if matched == want {
+				return nil
+			}
+		case field == "data":
+			data = append(data, strings.TrimPrefix(value, " "))
+		default:
+			// Comments, which servers send as heartbeats, and other fields
+			// are only signs of life.
+		}
+	}
+
+	if cause := context.Cause(ctx); errors.Is(cause, ErrHeartbeatMissed) {
+		return cause
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %d of %d before the deadline", ErrEventsMissing, matched, want)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: stream closed after %d of %d", ErrEventsMissing, matched, want)
+}
@@ -0,0 +1,155 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// eventServer streams events by path: /ticks every 20ms, /orders a ping
+// followed by an order, /silent one event and then nothing and /closed one
+// event before ending the stream. /plain is not an event stream.
+func eventServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			w.Write([]byte("data: tick\n\n")) //nolint
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		send := func(s string) {
+			fmt.Fprint(w, s)
+			w.(http.Flusher).Flush()
+		}
+
+		send(": connected\n\n")
+		switch r.URL.Path {
+		case "/ticks":
+			ticker := time.NewTicker(20 * time.Millisecond)
+			defer ticker.Stop()
+			for i := 0; ; i++ {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-ticker.C:
+					send(fmt.Sprintf("id: %d\ndata: tick %d\n\n", i, i))
+				}
+			}
+		case "/orders":
+			send("data: {\"type\": \"ping\"}\n\n")
+			send("event: order\r\ndata: {\"type\":\r\ndata: \"order\"}\r\n\r\n")
+		case "/closed":
+			send("data: tick\n\n")
+			return
+		case "/silent":
+			send("data: tick\n\n")
+		}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSSE_Run(t *testing.T) {
+	t.Parallel()
+
+	server := eventServer(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		policy     SSEPolicy
+		wantEvents int
+		wantErr    error
+	}{
+		// Test case for a stream checked with the default policy
+		// Verifies that the first event completes the probe
+		{
+			name:       "first event",
+			path:       "/ticks",
+			wantEvents: 1,
+		},
+		// Test case for a number of events within the deadline
+		// Verifies that the probe reads until enough events arrived
+		{
+			name:       "event count",
+			path:       "/ticks",
+			policy:     SSEPolicy{Events: 3, Deadline: time.Second, Heartbeat: 500 * time.Millisecond},
+			wantEvents: 3,
+		},
+		// Test case for an event matching a pattern across data lines
+		// Verifies that non-matching events are read but not counted
+		{
+			name:       "matching event",
+			path:       "/orders",
+			policy:     SSEPolicy{Match: regexp.MustCompile(`"type":\n"order"`)},
+			wantEvents: 2,
+		},
+		// Test case for a stream falling silent
+		// Verifies that the probe fails when the heartbeat interval passes without a line
+		{
+			name:       "heartbeat missed",
+			path:       "/silent",
+			policy:     SSEPolicy{Events: 2, Deadline: 5 * time.Second, Heartbeat: 50 * time.Millisecond},
+			wantEvents: 1,
+			wantErr:    ErrHeartbeatMissed,
+		},
+		// Test case for too few events before the deadline
+		// Verifies that the probe fails when the deadline passes
+		{
+			name:       "deadline",
+			path:       "/silent",
+			policy:     SSEPolicy{Events: 2, Deadline: 100 * time.Millisecond},
+			wantEvents: 1,
+			wantErr:    ErrEventsMissing,
+		},
+		// Test case for a stream ended by the server
+		// Verifies that the probe fails without waiting for the deadline
+		{
+			name:       "stream closed",
+			path:       "/closed",
+			policy:     SSEPolicy{Events: 2},
+			wantEvents: 1,
+			wantErr:    ErrEventsMissing,
+		},
+		// Test case for a response that is not an event stream
+		// Verifies that the content type is checked
+		{
+			name:    "not an event stream",
+			path:    "/plain",
+			wantErr: ErrNotEventStream,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			url := server.URL + tt.path
+			p := NewSSE(server.Client(), WithSSEPolicies(map[string]SSEPolicy{url: tt.policy}))
+			start := time.Now()
+			result := p.Run(context.Background(), Target{URL: url, Timeout: 10 * time.Second})
+
+			assert.Less(t, time.Since(start), 5*time.Second)
+			assert.Equal(t, 200, result.Status)
+			assert.Equal(t, tt.wantEvents, result.Events)
+			if tt.wantErr != nil {
+				assert.False(t, result.Success)
+				assert.True(t, errors.Is(result.Error, tt.wantErr), "got %v", result.Error)
+			} else {
+				assert.True(t, result.Success, "error: %v", result.Error)
+			}
+			if tt.wantEvents > 0 {
+				assert.Positive(t, result.FirstEvent)
+				assert.LessOrEqual(t, result.FirstEvent, result.Duration)
+			}
+		})
+	}
+}
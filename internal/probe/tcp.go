@@ -0,0 +1,148 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	neturl "net/url"
+	"regexp"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// CheckTCP is the check type of tcp://host:port targets.
+const CheckTCP = "tcp"
+
+// DefaultBannerSize is how much of a response is read while looking for the
+// expected banner.
+const DefaultBannerSize = 4 << 10
+
+var ErrBannerMismatch = errors.New("response does not match expected banner")
+
+// TCPPolicy is what a TCP probe does once connected. The zero TCPPolicy only
+// connects.
+type TCPPolicy struct {
+	Send       []byte
+	Expect     *regexp.Regexp
+	BannerSize int
+}
+
+func NewTCPPolicy(check config.TCPCheck) (TCPPolicy, error) {
+	p := TCPPolicy{Send: []byte(check.Send), BannerSize: check.BannerSize}
+	if check.Expect != "" {
+		re, err := regexp.Compile(check.Expect)
+		if err != nil {
+			return TCPPolicy{}, fmt.Errorf("expect pattern: %w", err)
+		}
+		p.Expect = re
+	}
+	return p, nil
+}
+
+// TCP checks that a port accepts connections, optionally sending a payload
+// and waiting for a banner matching a pattern. The connect time is reported
+// as the connection setup of a new connection.
+type TCP struct {
+	dialer   net.Dialer
+	policies map[string]TCPPolicy
+}
+
+type TCPOption func(*TCP)
+
+// WithTCPPolicies sets, per URL, the payload sent and the banner expected
+// after connecting.
+func WithTCPPolicies(policies map[string]TCPPolicy) TCPOption {
+	return func(p *TCP) {
+		p.policies = policies
+	}
+}
+
+func NewTCP(opts ...TCPOption) *TCP {
+	p := &TCP{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *TCP) Run(ctx context.Context, target Target) schema.RequestResult {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	u, err := neturl.Parse(target.URL)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	conn, err := p.dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Error = err
+		return result
+	}
+	result.ConnSetup = time.Since(start)
+	result.Connection = schema.ConnectionNew
+	defer conn.Close() //nolint
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now()) //nolint
+	})
+	defer stop()
+
+	n, err := p.policies[target.URL].exchange(conn)
+	result.Duration = time.Since(start)
+	result.PayloadSize = n
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// exchange sends the payload and reads until the banner matches, returning
+// the number of bytes read.
+func (p TCPPolicy) exchange(conn net.Conn) (int, error) {
+	if len(p.Send) > 0 {
+		if _, err := conn.Write(p.Send); err != nil {
+			return 0, err
+		}
+	}
+	if p.Expect == nil {
+		return 0, nil
+	}
+
+	size := p.BannerSize
+	if size <= 0 {
+		size = DefaultBannerSize
+	}
+	buf := make([]byte, size)
+	n := 0
+	for n < size {
+		read, err := conn.Read(buf[n:])
+		n += read
+		if p.Expect.Match(buf[:n]) {
+			return n, nil
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, ErrBannerMismatch
+}
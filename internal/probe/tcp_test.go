@@ -0,0 +1,137 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pingServer answers every PING line with +PONG, like Redis does.
+func pingServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() }) //nolint
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() //nolint
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					if scanner.Text() == "PING" {
+						conn.Write([]byte("+PONG\r\n")) //nolint
+					}
+				}
+			}()
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestTCP_Run(t *testing.T) {
+	t.Parallel()
+
+	url := pingServer(t)
+
+	tests := []struct {
+		name    string
+		policy  TCPPolicy
+		wantErr error
+	}{
+		// Test case for a plain connectivity check
+		// Verifies that accepting the connection is enough to succeed
+		{
+			name: "connect only",
+		},
+		// Test case for a payload answered with the expected banner
+		// Verifies that the probe succeeds once the response matches
+		{
+			name:   "expected banner",
+			policy: TCPPolicy{Send: []byte("PING\r\n"), Expect: regexp.MustCompile(`^\+PONG`)},
+		},
+		// Test case for a response not matching the expected banner
+		// Verifies that the probe fails once the banner size is read without a match
+		{
+			name:    "banner mismatch",
+			policy:  TCPPolicy{Send: []byte("PING\r\n"), Expect: regexp.MustCompile(`^-ERR`), BannerSize: 7},
+			wantErr: ErrBannerMismatch,
+		},
+		// Test case for a server that never answers
+		// Verifies that the probe gives up at the timeout
+		{
+			name:    "no answer",
+			policy:  TCPPolicy{Expect: regexp.MustCompile(`.`)},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewTCP(WithTCPPolicies(map[string]TCPPolicy{url: tt.policy}))
+			result := p.Run(context.Background(), Target{URL: url, Timeout: 100 * time.Millisecond})
+
+			assert.Equal(t, schema.ConnectionNew, result.Connection)
+			assert.Positive(t, result.ConnSetup)
+			assert.GreaterOrEqual(t, result.Duration, result.ConnSetup)
+			if tt.wantErr == nil {
+				assert.True(t, result.Success)
+				assert.NoError(t, result.Error)
+				return
+			}
+			assert.False(t, result.Success)
+			if errors.Is(tt.wantErr, context.DeadlineExceeded) {
+				var netErr net.Error
+				assert.True(t, errors.As(result.Error, &netErr) && netErr.Timeout(), "got %v", result.Error)
+			} else {
+				assert.True(t, errors.Is(result.Error, tt.wantErr), "got %v", result.Error)
+			}
+		})
+	}
+}
+
+func TestTCP_RunRefused(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a port nobody listens on
+	// Verifies that refused connections fail the probe and are not counted as connections
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := "tcp://" + ln.Addr().String()
+	ln.Close() //nolint
+
+	result := NewTCP().Run(context.Background(), Target{URL: url, Timeout: time.Second})
+
+	assert.False(t, result.Success)
+	assert.Error(t, result.Error)
+	assert.Empty(t, result.Connection)
+	assert.Zero(t, result.ConnSetup)
+}
+
+func TestNewTCPPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewTCPPolicy(config.TCPCheck{Send: "PING\r\n", Expect: `^\+PONG`})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("PING\r\n"), policy.Send)
+	assert.True(t, policy.Expect.MatchString("+PONG"))
+
+	_, err = NewTCPPolicy(config.TCPCheck{Expect: "[unclosed"})
+	assert.Error(t, err)
+}
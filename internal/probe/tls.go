@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// CheckTLS is the check type of tls://host:port targets.
+const CheckTLS = "tls"
+
+// TLS performs a TLS handshake and inspects the certificate chain presented
+// by the target. Unlike an https probe it records the certificate even when
+// the chain or the host name does not verify, in which case the probe fails.
+type TLS struct {
+	dialer   net.Dialer
+	roots    *x509.CertPool
+	policies map[string]CertPolicy
+}
+
+type TLSOption func(*TLS)
+
+// WithRootCAs sets the roots chains are verified against instead of the
+// system roots.
+func WithRootCAs(roots *x509.CertPool) TLSOption {
+	return func(p *TLS) {
+		p.roots = roots
+	}
+}
+
+// WithTLSCertPolicies sets, per URL, the certificate expiry thresholds.
+func WithTLSCertPolicies(policies map[string]CertPolicy) TLSOption {
+	return func(p *TLS) {
+		p.policies = policies
+	}
+}
+
+func NewTLS(opts ...TLSOption) *TLS {
+	p := &TLS{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *TLS) Run(ctx context.Context, target Target) schema.RequestResult {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	u, err := neturl.Parse(target.URL)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	host := u.Hostname()
+
+	dialer := tls.Dialer{
+		NetDialer: &p.dialer,
+		// The chain is verified below, so that its details are recorded even
+		// when it is not trusted.
+		Config: &tls.Config{ServerName: host, InsecureSkipVerify: true}, //nolint:gosec
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.ConnSetup = result.Duration
+	result.Connection = schema.ConnectionNew
+	defer conn.Close() //nolint
+
+	state := conn.(*tls.Conn).ConnectionState()
+	cert := newCertInfo(state, host)
+	if cert == nil {
+		result.Error = errors.New("no peer certificate")
+		return result
+	}
+	if err := verifyChain(state, p.roots, start); err != nil {
+		cert.ChainError = err.Error()
+		result.Error = fmt.Errorf("certificate chain: %w", err)
+	} else {
+		cert.ChainValid = true
+	}
+	if !cert.HostnameMatch && result.Error == nil {
+		result.Error = fmt.Errorf("%w %q", ErrHostnameMismatch, host)
+	}
+	result.Success = result.Error == nil
+
+	p.policies[target.URL].apply(&result, cert, start)
+	return result
+}
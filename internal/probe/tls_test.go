@@ -0,0 +1,222 @@
+package probe
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue returns a certificate for 127.0.0.1 and names, valid until notAfter.
+func (ca *testCA) issue(t *testing.T, notAfter time.Time, names ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return ca.issueFor(t, key, notAfter, names...)
+}
+
+// issueRSA is issue with an RSA key, which RSA key exchange needs.
+func (ca *testCA) issueRSA(t *testing.T, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return ca.issueFor(t, key, notAfter)
+}
+
+func (ca *testCA) issueFor(t *testing.T, key crypto.Signer, notAfter time.Time, names ...string) tls.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     names,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if len(names) == 0 {
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// tlsServer completes handshakes with cert and returns its tls:// URL.
+func tlsServer(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() }) //nolint
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()           //nolint
+				conn.(*tls.Conn).Handshake() //nolint
+				conn.Read(make([]byte, 1))   //nolint
+			}()
+		}
+	}()
+	return "tls://" + ln.Addr().String()
+}
+
+func TestTLS_Run(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	day := 24 * time.Hour
+	policy := CertPolicy{Warning: 21 * day, Critical: 7 * day}
+
+	tests := []struct {
+		name         string
+		cert         tls.Certificate
+		roots        *x509.CertPool
+		wantSuccess  bool
+		wantDegraded bool
+		wantState    string
+		wantErr      error
+	}{
+		// Test case for a certificate far from expiry
+		// Verifies that the probe succeeds and records the certificate
+		{
+			name:        "valid",
+			cert:        ca.issue(t, time.Now().Add(90*day)),
+			roots:       ca.pool,
+			wantSuccess: true,
+			wantState:   schema.CertOK,
+		},
+		// Test case for a certificate within the warning threshold
+		// Verifies that the probe succeeds but is degraded
+		{
+			name:         "warning",
+			cert:         ca.issue(t, time.Now().Add(14*day)),
+			roots:        ca.pool,
+			wantSuccess:  true,
+			wantDegraded: true,
+			wantState:    schema.CertWarning,
+		},
+		// Test case for a certificate within the critical threshold
+		// Verifies that the probe fails before the certificate expires
+		{
+			name:      "critical",
+			cert:      ca.issue(t, time.Now().Add(3*day)),
+			roots:     ca.pool,
+			wantState: schema.CertCritical,
+			wantErr:   ErrCertExpiring,
+		},
+		// Test case for a certificate for another host
+		// Verifies that host name mismatches fail the probe
+		{
+			name:      "hostname mismatch",
+			cert:      ca.issue(t, time.Now().Add(90*day), "other.example.com"),
+			roots:     ca.pool,
+			wantState: schema.CertOK,
+			wantErr:   ErrHostnameMismatch,
+		},
+		// Test case for a chain issued by an unknown authority
+		// Verifies that untrusted chains fail the probe but are still described
+		{
+			name:      "untrusted chain",
+			cert:      ca.issue(t, time.Now().Add(90*day)),
+			roots:     x509.NewCertPool(),
+			wantState: schema.CertOK,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			url := tlsServer(t, tt.cert)
+			p := NewTLS(WithRootCAs(tt.roots), WithTLSCertPolicies(map[string]CertPolicy{url: policy}))
+			result := p.Run(context.Background(), Target{URL: url, Timeout: time.Second})
+
+			require.NotNil(t, result.Cert, "error: %v", result.Error)
+			assert.Equal(t, tt.wantSuccess, result.Success, "error: %v", result.Error)
+			assert.Equal(t, tt.wantDegraded, result.Degraded)
+			assert.Equal(t, tt.wantState, result.Cert.State)
+			assert.Equal(t, "Test CA", result.Cert.Issuer)
+			assert.Equal(t, tt.roots == ca.pool, result.Cert.ChainValid)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(result.Error, tt.wantErr), "got %v", result.Error)
+			}
+		})
+	}
+}
+
+func TestHTTP_RunRecordsCertificate(t *testing.T) {
+	t.Parallel()
+
+	// Test case for an https target
+	// Verifies that the certificate the transport verified is recorded with its state
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	result := NewHTTP(server.Client()).Run(context.Background(), Target{URL: server.URL, Timeout: time.Second})
+
+	require.True(t, result.Success, "error: %v", result.Error)
+	require.NotNil(t, result.Cert)
+	assert.True(t, result.Cert.ChainValid)
+	assert.True(t, result.Cert.HostnameMatch)
+	assert.Equal(t, schema.CertOK, result.Cert.State)
+	assert.Equal(t, server.Certificate().NotAfter, result.Cert.NotAfter)
+	assert.Contains(t, result.Cert.DNSNames, "example.com")
+}
+
+func discardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
@@ -0,0 +1,74 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const unixPrefix = "unix://"
+
+// unixTarget splits a unix:///path/app.sock:/healthz target into the socket
+// path and the URL requested over it. The HTTP path defaults to /.
+func unixTarget(rawURL string) (socket, requestURL string, ok bool) {
+	rest, ok := strings.CutPrefix(rawURL, unixPrefix)
+	if !ok {
+		return "", "", false
+	}
+	socket, path, found := strings.Cut(rest, ":/")
+	if !found {
+		return socket, "http://localhost/", true
+	}
+	return socket, "http://localhost/" + path, true
+}
+
+// UnixSocket returns the socket path of a unix:// target.
+func UnixSocket(rawURL string) (string, bool) {
+	socket, _, ok := unixTarget(rawURL)
+	return socket, ok
+}
+
+// unixClients holds, per socket, copies of the probe's client whose
+// transport dials the socket instead of the request's host.
+type unixClients struct {
+	mutex   sync.Mutex
+	clients map[unixClientKey]*http.Client
+}
+
+type unixClientKey struct {
+	socket string
+	fresh  bool
+}
+
+func (u *unixClients) get(client *http.Client, socket string, fresh bool) *http.Client {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	key := unixClientKey{socket: socket, fresh: fresh}
+	if c, ok := u.clients[key]; ok {
+		return c
+	}
+
+	var transport *http.Transport
+	if t, ok := client.Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.DisableKeepAlives = fresh
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+
+	c := *client
+	c.Transport = transport
+	if u.clients == nil {
+		u.clients = make(map[unixClientKey]*http.Client)
+	}
+	u.clients[key] = &c
+	return &c
+}
@@ -0,0 +1,65 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixTarget(t *testing.T) {
+	t.Parallel()
+
+	socket, url, ok := unixTarget("unix:///var/run/app.sock:/healthz?full=1")
+	assert.True(t, ok)
+	assert.Equal(t, "/var/run/app.sock", socket)
+	assert.Equal(t, "http://localhost/healthz?full=1", url)
+
+	socket, url, ok = unixTarget("unix:///var/run/docker.sock")
+	assert.True(t, ok)
+	assert.Equal(t, "/var/run/docker.sock", socket)
+	assert.Equal(t, "http://localhost/", url)
+
+	_, _, ok = unixTarget("http://example.com")
+	assert.False(t, ok)
+}
+
+func TestHTTP_RunUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	// Test case for HTTP served on a unix socket in a temp dir
+	// Verifies that each target is requested over its own socket with its HTTP path
+	serve := func(name string) string {
+		socket := filepath.Join(t.TempDir(), name)
+		ln, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/healthz" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(name)) //nolint
+		})}
+		go server.Serve(ln)                  //nolint
+		t.Cleanup(func() { server.Close() }) //nolint
+		return socket
+	}
+	app, admin := serve("app.sock"), serve("admin.sock")
+
+	h := NewHTTP(&http.Client{})
+	for socket, want := range map[string]int{app: len("app.sock"), admin: len("admin.sock")} {
+		result := h.Run(context.Background(), Target{URL: "unix://" + socket + ":/healthz", Timeout: time.Second})
+		assert.True(t, result.Success, "error: %v", result.Error)
+		assert.Equal(t, 200, result.Status)
+		assert.Equal(t, want, result.PayloadSize)
+	}
+
+	result := h.Run(context.Background(), Target{URL: "unix://" + app + ":/missing", Timeout: time.Second})
+	assert.Equal(t, 404, result.Status)
+	assert.False(t, result.Success)
+}
@@ -0,0 +1,143 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"regexp"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"golang.org/x/net/websocket"
+)
+
+// CheckWebSocket is the check type of ws:// and wss:// targets.
+const CheckWebSocket = "websocket"
+
+var ErrReplyMismatch = errors.New("reply does not match")
+
+// MessagePolicy is the message sent after connecting and the pattern its
+// reply must match. The zero MessagePolicy only connects.
+type MessagePolicy struct {
+	Send   string
+	Expect *regexp.Regexp
+}
+
+func NewMessagePolicy(check config.MessageCheck) (MessagePolicy, error) {
+	p := MessagePolicy{Send: check.Send}
+	if check.Expect != "" {
+		re, err := regexp.Compile(check.Expect)
+		if err != nil {
+			return MessagePolicy{}, fmt.Errorf("expect pattern: %w", err)
+		}
+		p.Expect = re
+	}
+	return p, nil
+}
+
+// WebSocket performs the upgrade handshake, reported as the setup of a new
+// connection, and optionally times a message round trip.
+type WebSocket struct {
+	dialer   net.Dialer
+	policies map[string]MessagePolicy
+}
+
+type WebSocketOption func(*WebSocket)
+
+// WithMessagePolicies sets, per URL, the message sent after the handshake
+// and the reply expected.
+func WithMessagePolicies(policies map[string]MessagePolicy) WebSocketOption {
+	return func(p *WebSocket) {
+		p.policies = policies
+	}
+}
+
+func NewWebSocket(opts ...WebSocketOption) *WebSocket {
+	p := &WebSocket{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *WebSocket) Run(ctx context.Context, target Target) schema.RequestResult {
+	if target.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := schema.RequestResult{
+		Timestamp: start,
+		URL:       target.URL,
+	}
+
+	cfg, err := websocketConfig(target.URL)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	cfg.Dialer = &p.dialer
+
+	ws, err := cfg.DialContext(ctx)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.ConnSetup = result.Duration
+	result.Connection = schema.ConnectionNew
+	defer ws.Close() //nolint
+	stop := context.AfterFunc(ctx, func() {
+		ws.SetDeadline(time.Now()) //nolint
+	})
+	defer stop()
+
+	policy := p.policies[target.URL]
+	if policy.Send != "" {
+		sent := time.Now()
+		reply, err := policy.exchange(ws)
+		result.Duration = time.Since(start)
+		result.PayloadSize = len(reply)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		result.RoundTrip = time.Since(sent)
+	}
+
+	result.Success = true
+	return result
+}
+
+// websocketConfig derives the handshake from the URL; the origin is the
+// target's own http or https origin.
+func websocketConfig(rawURL string) (*websocket.Config, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	origin := &neturl.URL{Scheme: "http", Host: u.Host}
+	if u.Scheme == "wss" {
+		origin.Scheme = "https"
+	}
+	return websocket.NewConfig(rawURL, origin.String())
+}
+
+func (p MessagePolicy) exchange(ws *websocket.Conn) (string, error) {
+	if err := websocket.Message.Send(ws, p.Send); err != nil {
+		return "", err
+	}
+	var reply string
+	if err := websocket.Message.Receive(ws, &reply); err != nil {
+		return "", err
+	}
+	if p.Expect != nil && !p.Expect.MatchString(reply) {
+		return reply, fmt.Errorf("%w %q: %q", ErrReplyMismatch, p.Expect, reply)
+	}
+	return reply, nil
+}
@@ -0,0 +1,112 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocket_Run(t *testing.T) {
+	t.Parallel()
+
+	// pong answers every message with "pong " and the message.
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		var msg string
+		for websocket.Message.Receive(ws, &msg) == nil {
+			websocket.Message.Send(ws, "pong "+msg) //nolint
+		}
+	}))
+	t.Cleanup(server.Close)
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	plain := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(plain.Close)
+
+	tests := []struct {
+		name          string
+		url           string
+		policy        MessagePolicy
+		wantSuccess   bool
+		wantRoundTrip bool
+		wantErr       error
+		// noConnection is set when no websocket connection is set up.
+		noConnection bool
+	}{
+		// Test case for a plain upgrade handshake
+		// Verifies that completing the handshake is enough to succeed
+		{
+			name:        "handshake only",
+			url:         url,
+			wantSuccess: true,
+		},
+		// Test case for a message answered as expected
+		// Verifies that the round trip is measured
+		{
+			name:          "expected reply",
+			url:           url,
+			policy:        MessagePolicy{Send: "ping", Expect: regexp.MustCompile(`^pong ping$`)},
+			wantSuccess:   true,
+			wantRoundTrip: true,
+		},
+		// Test case for a reply not matching the pattern
+		// Verifies that the probe fails
+		{
+			name:    "unexpected reply",
+			url:     url,
+			policy:  MessagePolicy{Send: "ping", Expect: regexp.MustCompile(`^ok$`)},
+			wantErr: ErrReplyMismatch,
+		},
+		// Test case for a path that does not upgrade
+		// Verifies that a failed handshake fails the probe and counts no connection
+		{
+			name:         "no upgrade",
+			url:          "ws" + strings.TrimPrefix(plain.URL, "http"),
+			noConnection: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewWebSocket(WithMessagePolicies(map[string]MessagePolicy{tt.url: tt.policy}))
+			result := p.Run(context.Background(), Target{URL: tt.url, Timeout: time.Second})
+
+			assert.Equal(t, tt.wantSuccess, result.Success, "error: %v", result.Error)
+			if tt.noConnection {
+				assert.Empty(t, result.Connection)
+				assert.Zero(t, result.ConnSetup)
+			} else {
+				assert.Equal(t, schema.ConnectionNew, result.Connection)
+				assert.Positive(t, result.ConnSetup)
+			}
+			assert.Equal(t, tt.wantRoundTrip, result.RoundTrip > 0)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(result.Error, tt.wantErr), "got %v", result.Error)
+			}
+		})
+	}
+}
+
+func TestNewMessagePolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewMessagePolicy(config.MessageCheck{Send: "ping", Expect: "^pong"})
+	require.NoError(t, err)
+	assert.True(t, policy.Expect.MatchString("pong"))
+
+	_, err = NewMessagePolicy(config.MessageCheck{Send: "ping", Expect: "(unclosed"})
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package processor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"sync"
@@ -9,47 +11,99 @@ import (
 
 	"github.com/dvdk01/http-status-monitor/internal/application"
 	"github.com/dvdk01/http-status-monitor/internal/monitor"
-	log "github.com/sirupsen/logrus"
+	"github.com/dvdk01/http-status-monitor/internal/state"
 )
 
+// ErrForcedExit is returned when a second signal arrives before the shutdown
+// finished.
+var ErrForcedExit = errors.New("forced exit before shutdown finished")
+
 type processor struct {
 	monitor     monitor.Monitor
 	application application.Application
+	snapshotter *state.Snapshotter
+}
+
+type Option func(*processor)
+
+// WithSnapshotter periodically persists the monitor stats and writes a final
+// snapshot on shutdown.
+func WithSnapshotter(snapshotter *state.Snapshotter) Option {
+	return func(p *processor) {
+		p.snapshotter = snapshotter
+	}
 }
 
-func New(monitor monitor.Monitor, display application.Application) *processor {
-	return &processor{monitor: monitor, application: display}
+func New(monitor monitor.Monitor, display application.Application, opts ...Option) *processor {
+	p := &processor{monitor: monitor, application: display}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
-func (m *processor) Start() {
+// Start runs the monitor and the application until SIGINT or SIGTERM, or
+// until either of them fails. A second signal abandons the shutdown.
+func (m *processor) Start() error {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	return m.run(signals)
+}
+
+func (m *processor) run(signals <-chan os.Signal) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	errs := make(chan error, 2)
 	var wg sync.WaitGroup
+	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		wg.Add(1)
 		if err := m.monitor.Start(ctx); err != nil {
-			log.WithError(err).Error("failed to start monitor")
-			os.Exit(1)
+			errs <- fmt.Errorf("monitor: %w", err)
 		}
 	}()
-
 	go func() {
 		defer wg.Done()
-		wg.Add(1)
 		if err := m.application.Start(ctx); err != nil {
-			log.WithError(err).Error("failed to start application")
-			os.Exit(1)
+			errs <- fmt.Errorf("application: %w", err)
 		}
 	}()
 
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
+	if m.snapshotter != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.snapshotter.Run(ctx, m.monitor.GetStats)
+		}()
+	}
 
-	<-sigterm
+	var err error
+	select {
+	case <-signals:
+	case err = <-errs:
+	}
 	cancel()
-	wg.Wait()
 
-	m.application.Render(m.monitor.GetStats())
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-signals:
+		return ErrForcedExit
+	}
+
+	stats := m.monitor.GetStats()
+	if m.snapshotter != nil {
+		if saveErr := m.snapshotter.Save(stats); saveErr != nil {
+			err = errors.Join(err, fmt.Errorf("save final stats snapshot: %w", saveErr))
+		}
+	}
+	m.application.Render(stats)
+	return err
 }
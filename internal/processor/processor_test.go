@@ -0,0 +1,141 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/dvdk01/http-status-monitor/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMonitor struct {
+	started chan struct{}
+	err     error
+	// hang keeps Start from returning after its context is cancelled.
+	hang chan struct{}
+}
+
+func newFakeMonitor() *fakeMonitor {
+	return &fakeMonitor{started: make(chan struct{})}
+}
+
+func (f *fakeMonitor) Start(ctx context.Context) error {
+	close(f.started)
+	if f.err != nil {
+		return f.err
+	}
+	<-ctx.Done()
+	if f.hang != nil {
+		<-f.hang
+	}
+	return nil
+}
+
+func (f *fakeMonitor) Stop() error { return nil }
+
+func (f *fakeMonitor) GetStats() map[string]*schema.URLStats {
+	return map[string]*schema.URLStats{"http://example.com": {URL: "http://example.com", TotalRequests: 3, StatusCodes: map[int]int{200: 3}}}
+}
+
+func (f *fakeMonitor) GetStatsSince(uint64) (map[string]*schema.URLStats, uint64) {
+	return f.GetStats(), 1
+}
+
+func (f *fakeMonitor) Subscribe() (<-chan map[string]*schema.URLStats, func()) {
+	return make(chan map[string]*schema.URLStats), func() {}
+}
+
+type fakeApplication struct {
+	ctx      chan context.Context
+	rendered chan map[string]*schema.URLStats
+}
+
+func newFakeApplication() *fakeApplication {
+	return &fakeApplication{ctx: make(chan context.Context, 1), rendered: make(chan map[string]*schema.URLStats, 1)}
+}
+
+func (f *fakeApplication) Start(ctx context.Context) error {
+	f.ctx <- ctx
+	return nil
+}
+
+func (f *fakeApplication) Render(stats map[string]*schema.URLStats) {
+	f.rendered <- stats
+}
+
+func TestProcessor_runSignal(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a clean shutdown on the first signal
+	// Verifies that everything stops, the final snapshot is saved and rendered, and no error is returned
+	path := filepath.Join(t.TempDir(), "state.json")
+	mon, app := newFakeMonitor(), newFakeApplication()
+	p := New(mon, app, WithSnapshotter(state.NewSnapshotter(path, time.Hour)))
+
+	signals := make(chan os.Signal, 2)
+	done := make(chan error)
+	go func() {
+		done <- p.run(signals)
+	}()
+	<-mon.started
+	signals <- syscall.SIGTERM
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, 3, (<-app.rendered)["http://example.com"].TotalRequests)
+	assert.Error(t, (<-app.ctx).Err())
+
+	saved, err := state.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, saved["http://example.com"].TotalRequests)
+}
+
+func TestProcessor_runMonitorError(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a monitor failing while running
+	// Verifies that the error is returned and the application is stopped
+	mon, app := newFakeMonitor(), newFakeApplication()
+	mon.err = errors.New("boom")
+	p := New(mon, app)
+
+	err := p.run(make(chan os.Signal))
+	assert.ErrorContains(t, err, "monitor: boom")
+	assert.Error(t, (<-app.ctx).Err())
+	assert.NotNil(t, <-app.rendered)
+}
+
+func TestProcessor_runSecondSignalForcesExit(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a monitor that does not stop after the first signal
+	// Verifies that a second signal abandons the shutdown
+	mon, app := newFakeMonitor(), newFakeApplication()
+	mon.hang = make(chan struct{})
+	defer close(mon.hang)
+	p := New(mon, app)
+
+	signals := make(chan os.Signal, 2)
+	done := make(chan error)
+	go func() {
+		done <- p.run(signals)
+	}()
+	<-mon.started
+	signals <- syscall.SIGINT
+
+	select {
+	case <-done:
+		t.Fatal("shutdown finished although the monitor hangs")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	signals <- syscall.SIGINT
+	assert.ErrorIs(t, <-done, ErrForcedExit)
+	assert.Empty(t, app.rendered)
+}
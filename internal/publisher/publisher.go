@@ -0,0 +1,158 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+// Publisher hands stats snapshots to its subscribers. Changes are signalled
+// with Notify and coalesced: a snapshot is taken at most once per minimum
+// interval, and a subscriber that has not yet received the previous snapshot
+// gets the newer one in its place.
+type Publisher struct {
+	source      func() map[string]*schema.URLStats
+	minInterval time.Duration
+	changed     chan struct{}
+
+	mutex       sync.Mutex
+	subscribers map[*subscription]struct{}
+	closed      bool
+}
+
+type subscription struct {
+	ch chan map[string]*schema.URLStats
+}
+
+type Option func(*Publisher)
+
+// WithMinInterval bounds how often snapshots are taken and delivered, no
+// matter how often Notify is called.
+func WithMinInterval(interval time.Duration) Option {
+	return func(p *Publisher) {
+		p.minInterval = interval
+	}
+}
+
+// New creates a publisher taking its snapshots from source.
+func New(source func() map[string]*schema.URLStats, opts ...Option) *Publisher {
+	p := &Publisher{
+		source:      source,
+		changed:     make(chan struct{}, 1),
+		subscribers: make(map[*subscription]struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Subscribe returns a channel delivering the latest snapshot and a function
+// cancelling the subscription. The channel is closed on cancel and when the
+// publisher stops. Snapshots are shared between subscribers and must not be
+// modified.
+func (p *Publisher) Subscribe() (<-chan map[string]*schema.URLStats, func()) {
+	sub := &subscription{ch: make(chan map[string]*schema.URLStats, 1)}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	p.subscribers[sub] = struct{}{}
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			if _, ok := p.subscribers[sub]; ok {
+				delete(p.subscribers, sub)
+				close(sub.ch)
+			}
+		})
+	}
+}
+
+// Notify signals that the stats changed. It never blocks.
+func (p *Publisher) Notify() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes snapshots until ctx is done and then closes every
+// subscription.
+func (p *Publisher) Run(ctx context.Context) {
+	defer p.close()
+
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.changed:
+		}
+
+		if wait := p.minInterval - time.Since(last); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			// Changes signalled while waiting are part of this snapshot.
+			select {
+			case <-p.changed:
+			default:
+			}
+		}
+
+		p.publish()
+		last = time.Now()
+	}
+}
+
+func (p *Publisher) publish() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.subscribers) == 0 {
+		return
+	}
+	stats := p.source()
+	for sub := range p.subscribers {
+		sub.offer(stats)
+	}
+}
+
+// offer replaces an undelivered snapshot instead of waiting for the reader.
+func (s *subscription) offer(stats map[string]*schema.URLStats) {
+	for {
+		select {
+		case s.ch <- stats:
+			return
+		default:
+		}
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+func (p *Publisher) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+	for sub := range p.subscribers {
+		delete(p.subscribers, sub)
+		close(sub.ch)
+	}
+}
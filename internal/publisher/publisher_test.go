@@ -0,0 +1,148 @@
+package publisher
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func counter() (func() map[string]*schema.URLStats, *int32) {
+	var calls int32
+	return func() map[string]*schema.URLStats {
+		n := atomic.AddInt32(&calls, 1)
+		return map[string]*schema.URLStats{"http://example.com": {TotalRequests: int(n)}}
+	}, &calls
+}
+
+func TestPublisher_CoalescesForSlowSubscriber(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a subscriber that does not read while many changes happen
+	// Verifies that it later receives only the latest snapshot
+	source, _ := counter()
+	p := New(source)
+	ch, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	for i := 0; i < 100; i++ {
+		p.Notify()
+		time.Sleep(100 * time.Microsecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	first := <-ch
+	select {
+	case <-ch:
+		t.Fatal("stale snapshot was queued")
+	default:
+	}
+
+	p.Notify()
+	second := <-ch
+	assert.Greater(t, second["http://example.com"].TotalRequests, first["http://example.com"].TotalRequests)
+}
+
+func TestPublisher_MinInterval(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a burst of changes under a minimum interval
+	// Verifies that snapshots are taken at most once per interval
+	source, calls := counter()
+	p := New(source, WithMinInterval(50*time.Millisecond))
+	ch, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	deadline := time.Now().Add(120 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		p.Notify()
+		select {
+		case <-ch:
+		default:
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.LessOrEqual(t, atomic.LoadInt32(calls), int32(3))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(calls), int32(2))
+}
+
+func TestPublisher_SkipsSnapshotWithoutSubscribers(t *testing.T) {
+	t.Parallel()
+
+	// Test case for changes while nobody listens
+	// Verifies that no snapshot is taken
+	source, calls := counter()
+	p := New(source)
+	ch, unsubscribe := p.Subscribe()
+	unsubscribe()
+
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	p.Notify()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(calls))
+}
+
+func TestPublisher_NoGoroutineLeakAfterShutdown(t *testing.T) {
+	// Not parallel: it counts the goroutines of the whole test binary.
+
+	// Test case for a publisher with subscribers that stopped reading
+	// Verifies that shutdown closes every subscription and leaves no goroutines behind
+	before := runtime.NumGoroutine()
+
+	source, _ := counter()
+	p := New(source)
+	subscriptions := make([]<-chan map[string]*schema.URLStats, 10)
+	for i := range subscriptions {
+		subscriptions[i], _ = p.Subscribe()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 1000; i++ {
+		p.Notify()
+	}
+	cancel()
+	<-done
+
+	for _, ch := range subscriptions {
+		for range ch {
+		}
+	}
+	ch, _ := p.Subscribe()
+	_, ok := <-ch
+	assert.False(t, ok, "subscribing after shutdown returns a closed channel")
+
+	// Polled by hand: assert.Eventually runs goroutines of its own.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
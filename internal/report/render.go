@@ -0,0 +1,99 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func RenderJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func RenderTable(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "Report from %s to %s\n", r.Since.Format(time.RFC3339), r.Until.Format(time.RFC3339))
+	if r.Skipped > 0 {
+		fmt.Fprintf(w, "Skipped %d corrupted records in the result log\n", r.Skipped)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{
+		"URL", "Uptime", "Probes",
+		"P50", "P90", "P95", "P99", "Max",
+		"Incidents", "Status Codes",
+	})
+	for _, target := range r.Targets {
+		t.AppendRow(table.Row{
+			target.URL,
+			fmt.Sprintf("%.2f%%", target.Uptime),
+			fmt.Sprintf("%d/%d", target.Successes, target.Probes),
+			target.Latency.P50.Round(time.Millisecond),
+			target.Latency.P90.Round(time.Millisecond),
+			target.Latency.P95.Round(time.Millisecond),
+			target.Latency.P99.Round(time.Millisecond),
+			target.Latency.Max.Round(time.Millisecond),
+			len(target.Incidents),
+			formatStatusCodes(target.StatusCodes, target.Codes),
+		})
+	}
+	t.Render()
+
+	incidents := table.NewWriter()
+	incidents.SetOutputMirror(w)
+	incidents.SetStyle(table.StyleLight)
+	incidents.AppendHeader(table.Row{"URL", "Start", "End", "Duration", "Failures", "First Error"})
+	count := 0
+	for _, target := range r.Targets {
+		for _, inc := range target.Incidents {
+			end := inc.End.Format(time.RFC3339)
+			if inc.Ongoing {
+				end = "ongoing"
+			}
+			incidents.AppendRow(table.Row{
+				target.URL,
+				inc.Start.Format(time.RFC3339),
+				end,
+				inc.Duration.Round(time.Second),
+				inc.Failures,
+				inc.FirstError,
+			})
+			count++
+		}
+	}
+	if count > 0 {
+		incidents.Render()
+	}
+}
+
+func formatStatusCodes(statuses map[int]int, codes map[string]int) string {
+	if len(statuses) == 0 && len(codes) == 0 {
+		return "NO STATUS CODE"
+	}
+	keys := make([]int, 0, len(statuses))
+	for status := range statuses {
+		keys = append(keys, status)
+	}
+	sort.Ints(keys)
+	parts := make([]string, 0, len(keys)+len(codes))
+	for _, status := range keys {
+		parts = append(parts, fmt.Sprintf("%d:%d", status, statuses[status]))
+	}
+	names := make([]string, 0, len(codes))
+	for code := range codes {
+		names = append(names, code)
+	}
+	sort.Strings(names)
+	for _, code := range names {
+		parts = append(parts, fmt.Sprintf("%s:%d", code, codes[code]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,196 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/resultlog"
+)
+
+type Incident struct {
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	Duration   time.Duration `json:"duration"`
+	Failures   int           `json:"failures"`
+	FirstError string        `json:"first_error,omitempty"`
+	Ongoing    bool          `json:"ongoing"`
+}
+
+type Latency struct {
+	Min time.Duration `json:"min"`
+	Avg time.Duration `json:"avg"`
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+type TargetReport struct {
+	URL         string      `json:"url"`
+	Probes      int         `json:"probes"`
+	Successes   int         `json:"successes"`
+	Uptime      float64     `json:"uptime_percent"`
+	Latency     Latency     `json:"latency"`
+	StatusCodes map[int]int `json:"status_codes"`
+	// Codes counts the response codes of checks other than http.
+	Codes        map[string]int `json:"codes,omitempty"`
+	Errors       int            `json:"errors"`
+	UpstreamDown int            `json:"upstream_down"`
+	Incidents    []Incident     `json:"incidents"`
+}
+
+type Report struct {
+	Since   time.Time      `json:"since"`
+	Until   time.Time      `json:"until"`
+	Targets []TargetReport `json:"targets"`
+	// Skipped counts corrupted records left out of the report.
+	Skipped int `json:"skipped_records,omitempty"`
+}
+
+type accumulator struct {
+	report    TargetReport
+	durations []time.Duration
+	total     time.Duration
+	open      *Incident
+	lastSeen  time.Time
+}
+
+func (a *accumulator) add(rec resultlog.Record) {
+	r := &a.report
+	r.Probes++
+	a.durations = append(a.durations, rec.Duration)
+	a.total += rec.Duration
+	if rec.Status > 0 {
+		r.StatusCodes[rec.Status]++
+	}
+	if rec.Code != "" {
+		if r.Codes == nil {
+			r.Codes = make(map[string]int)
+		}
+		r.Codes[rec.Code]++
+	}
+	if rec.Error != "" {
+		r.Errors++
+	}
+	if rec.UpstreamDown != "" {
+		r.UpstreamDown++
+	}
+
+	if rec.Success {
+		r.Successes++
+		if a.open != nil {
+			a.open.End = rec.Time
+			a.open.Duration = a.open.End.Sub(a.open.Start)
+			r.Incidents = append(r.Incidents, *a.open)
+			a.open = nil
+		}
+	} else {
+		if a.open == nil {
+			a.open = &Incident{Start: rec.Time, FirstError: firstError(rec)}
+		}
+		a.open.Failures++
+	}
+	a.lastSeen = rec.Time
+}
+
+func firstError(rec resultlog.Record) string {
+	if rec.Error != "" {
+		return rec.Error
+	}
+	if rec.Status > 0 {
+		return fmt.Sprintf("status %d", rec.Status)
+	}
+	return rec.Code
+}
+
+func (a *accumulator) finish() TargetReport {
+	r := a.report
+	if a.open != nil {
+		a.open.End = a.lastSeen
+		a.open.Duration = a.open.End.Sub(a.open.Start)
+		a.open.Ongoing = true
+		r.Incidents = append(r.Incidents, *a.open)
+	}
+	if r.Probes > 0 {
+		r.Uptime = 100 * float64(r.Successes) / float64(r.Probes)
+		sort.Slice(a.durations, func(i, j int) bool { return a.durations[i] < a.durations[j] })
+		r.Latency = Latency{
+			Min: a.durations[0],
+			Avg: a.total / time.Duration(r.Probes),
+			P50: percentile(a.durations, 50),
+			P90: percentile(a.durations, 90),
+			P95: percentile(a.durations, 95),
+			P99: percentile(a.durations, 99),
+			Max: a.durations[len(a.durations)-1],
+		}
+	}
+	return r
+}
+
+// percentile uses the nearest-rank method on sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Build reads the result log in dir and aggregates every record within
+// [since, until) per target. An empty targets list selects all targets.
+func Build(dir string, since, until time.Time, targets []string) (*Report, error) {
+	selected := make(map[string]bool)
+	for _, t := range targets {
+		selected[t] = true
+	}
+
+	accumulators := make(map[string]*accumulator)
+	err := resultlog.Read(dir, since, until, func(rec resultlog.Record) error {
+		if len(selected) > 0 && !selected[rec.URL] {
+			return nil
+		}
+		acc, ok := accumulators[rec.URL]
+		if !ok {
+			acc = &accumulator{report: TargetReport{URL: rec.URL, StatusCodes: make(map[int]int)}}
+			accumulators[rec.URL] = acc
+		}
+		acc.add(rec)
+		return nil
+	})
+	report := &Report{Since: since, Until: until}
+	var corrupt *resultlog.CorruptError
+	if errors.As(err, &corrupt) {
+		report.Skipped = corrupt.Skipped
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, acc := range accumulators {
+		report.Targets = append(report.Targets, acc.finish())
+	}
+	sort.Slice(report.Targets, func(i, j int) bool {
+		return report.Targets[i].URL < report.Targets[j].URL
+	})
+	return report, nil
+}
+
+// ParseTime accepts "now", a duration meaning that long before now
+// (e.g. "24h"), or an RFC 3339 timestamp.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "now" {
+		return now, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use now, a duration like 24h or an RFC 3339 timestamp", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,210 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/resultlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var epoch = time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+
+func writeLog(t *testing.T, records []resultlog.Record) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	w, err := resultlog.Open(resultlog.Options{Dir: dir})
+	require.NoError(t, err)
+	for _, rec := range records {
+		require.NoError(t, w.Write(rec))
+	}
+	require.NoError(t, w.Close())
+	return dir
+}
+
+func probe(url string, minute int, ms int, status int, success bool) resultlog.Record {
+	rec := resultlog.Record{
+		Time:     epoch.Add(time.Duration(minute) * time.Minute),
+		URL:      url,
+		Duration: time.Duration(ms) * time.Millisecond,
+		Status:   status,
+		Success:  success,
+	}
+	if status == 0 {
+		rec.Error = "connection refused"
+	}
+	return rec
+}
+
+func TestBuild(t *testing.T) {
+	// Test case for a day with one resolved and one ongoing incident
+	// Verifies uptime, percentiles, status code breakdown and the incident list
+	var records []resultlog.Record
+	for i := 0; i < 100; i++ {
+		status, success := 200, true
+		switch {
+		case i >= 10 && i < 13:
+			status, success = 503, false
+		case i >= 98:
+			status, success = 0, false
+		}
+		records = append(records, probe("https://a.com", i, i+1, status, success))
+		records = append(records, probe("https://b.com", i, 5, 200, true))
+	}
+	dir := writeLog(t, records)
+
+	r, err := Build(dir, epoch, epoch.Add(time.Hour*2), nil)
+	require.NoError(t, err)
+	require.Len(t, r.Targets, 2)
+
+	a := r.Targets[0]
+	assert.Equal(t, "https://a.com", a.URL)
+	assert.Equal(t, 100, a.Probes)
+	assert.Equal(t, 95, a.Successes)
+	assert.InDelta(t, 95.0, a.Uptime, 0.001)
+	assert.Equal(t, time.Millisecond, a.Latency.Min)
+	assert.Equal(t, 50*time.Millisecond, a.Latency.P50)
+	assert.Equal(t, 90*time.Millisecond, a.Latency.P90)
+	assert.Equal(t, 99*time.Millisecond, a.Latency.P99)
+	assert.Equal(t, 100*time.Millisecond, a.Latency.Max)
+	assert.Equal(t, map[int]int{200: 95, 503: 3}, a.StatusCodes)
+	assert.Equal(t, 2, a.Errors)
+
+	require.Len(t, a.Incidents, 2)
+	assert.Equal(t, epoch.Add(10*time.Minute), a.Incidents[0].Start.UTC())
+	assert.Equal(t, 3*time.Minute, a.Incidents[0].Duration)
+	assert.Equal(t, 3, a.Incidents[0].Failures)
+	assert.Equal(t, "status 503", a.Incidents[0].FirstError)
+	assert.False(t, a.Incidents[0].Ongoing)
+	assert.True(t, a.Incidents[1].Ongoing)
+	assert.Equal(t, "connection refused", a.Incidents[1].FirstError)
+
+	b := r.Targets[1]
+	assert.InDelta(t, 100.0, b.Uptime, 0.001)
+	assert.Empty(t, b.Incidents)
+}
+
+func TestBuild_Filters(t *testing.T) {
+	// Test case for narrowing the report to a target and a time range
+	// Verifies that records outside the selection are ignored
+	var records []resultlog.Record
+	for i := 0; i < 60; i++ {
+		records = append(records, probe("https://a.com", i, 10, 200, true))
+		records = append(records, probe("https://b.com", i, 10, 200, true))
+	}
+	dir := writeLog(t, records)
+
+	r, err := Build(dir, epoch.Add(30*time.Minute), epoch.Add(40*time.Minute), []string{"https://b.com"})
+	require.NoError(t, err)
+	require.Len(t, r.Targets, 1)
+	assert.Equal(t, "https://b.com", r.Targets[0].URL)
+	assert.Equal(t, 10, r.Targets[0].Probes)
+}
+
+func TestBuild_Codes(t *testing.T) {
+	// Test case for a dns target whose results carry codes instead of status codes
+	// Verifies that the codes are counted and name incidents without an error
+	nx := resultlog.Record{Time: epoch.Add(time.Minute), URL: "dns:///a.com", CheckType: "dns", Code: "NXDOMAIN"}
+	ok := resultlog.Record{Time: epoch, URL: "dns:///a.com", CheckType: "dns", Code: "NOERROR", Success: true}
+	dir := writeLog(t, []resultlog.Record{ok, nx})
+
+	r, err := Build(dir, time.Time{}, time.Time{}, nil)
+	require.NoError(t, err)
+	require.Len(t, r.Targets, 1)
+	assert.Equal(t, map[string]int{"NOERROR": 1, "NXDOMAIN": 1}, r.Targets[0].Codes)
+	require.Len(t, r.Targets[0].Incidents, 1)
+	assert.Equal(t, "NXDOMAIN", r.Targets[0].Incidents[0].FirstError)
+}
+
+func TestBuild_CorruptRecord(t *testing.T) {
+	// Test case for a result log with a damaged line
+	// Verifies that the report is built from the other records and counts the skipped one
+	dir := writeLog(t, []resultlog.Record{probe("https://a.com", 0, 10, 200, true)})
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	file, err := os.OpenFile(filepath.Join(dir, entries[0].Name()), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString("not json\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	r, err := Build(dir, time.Time{}, time.Time{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Skipped)
+	require.Len(t, r.Targets, 1)
+	assert.Equal(t, 1, r.Targets[0].Probes)
+
+	var out bytes.Buffer
+	RenderTable(&out, r)
+	assert.Contains(t, out.String(), "Skipped 1 corrupted records")
+}
+
+func TestParseTime(t *testing.T) {
+	now := epoch
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		// Test case for the literal now
+		// Verifies that now resolves to the reference time
+		{name: "now", value: "now", expected: now},
+		// Test case for a relative duration
+		// Verifies that a duration is subtracted from now
+		{name: "relative", value: "24h", expected: now.Add(-24 * time.Hour)},
+		// Test case for an absolute timestamp
+		// Verifies that RFC 3339 timestamps are used as is
+		{name: "absolute", value: "2026-03-01T00:00:00Z", expected: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// Test case for an unparseable value
+		// Verifies that garbage is rejected
+		{name: "invalid", value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(got))
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	// Test case for rendering a report
+	// Verifies that both the table and the JSON output contain the report data
+	dir := writeLog(t, []resultlog.Record{
+		probe("https://a.com", 0, 10, 200, true),
+		probe("https://a.com", 1, 10, 500, false),
+		probe("https://a.com", 2, 10, 200, true),
+	})
+	r, err := Build(dir, epoch, epoch.Add(time.Hour), nil)
+	require.NoError(t, err)
+
+	var table bytes.Buffer
+	RenderTable(&table, r)
+	assert.Contains(t, table.String(), "https://a.com")
+	assert.Contains(t, table.String(), "66.67%")
+	assert.Contains(t, table.String(), "200:2 500:1")
+	assert.Contains(t, table.String(), "status 500")
+
+	var out bytes.Buffer
+	require.NoError(t, RenderJSON(&out, r))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded.Targets, 1)
+	assert.Equal(t, 3, decoded.Targets[0].Probes)
+	assert.Len(t, decoded.Targets[0].Incidents, 1)
+}
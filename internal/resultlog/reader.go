@@ -0,0 +1,208 @@
+package resultlog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	segmentPrefix     = "results-"
+	segmentTimeLayout = "20060102T150405.000000000Z"
+)
+
+type segment struct {
+	path       string
+	start      time.Time
+	format     Format
+	compressed bool
+}
+
+func segmentName(start time.Time, format Format) string {
+	return segmentPrefix + start.UTC().Format(segmentTimeLayout) + "." + string(format)
+}
+
+func parseSegmentName(name string) (segment, bool) {
+	if !strings.HasPrefix(name, segmentPrefix) {
+		return segment{}, false
+	}
+	seg := segment{}
+	rest := strings.TrimPrefix(name, segmentPrefix)
+	if strings.HasSuffix(rest, ".gz") {
+		seg.compressed = true
+		rest = strings.TrimSuffix(rest, ".gz")
+	}
+
+	dot := strings.LastIndex(rest, ".")
+	if dot < 0 {
+		return segment{}, false
+	}
+	seg.format = Format(rest[dot+1:])
+	if seg.format != FormatJSON && seg.format != FormatBinary {
+		return segment{}, false
+	}
+
+	start, err := time.Parse(segmentTimeLayout, rest[:dot])
+	if err != nil {
+		return segment{}, false
+	}
+	seg.start = start
+	return seg, true
+}
+
+// listSegments returns the segments in dir ordered from oldest to newest.
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("list result log: %w", err)
+	}
+
+	var segments []segment
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		seg, ok := parseSegmentName(e.Name())
+		if !ok {
+			continue
+		}
+		seg.path = filepath.Join(dir, e.Name())
+		segments = append(segments, seg)
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+	return segments, nil
+}
+
+// CorruptError reports records that Read skipped because they could not be
+// decoded.
+type CorruptError struct {
+	Skipped int
+	// First is the first skipped record's error, naming its segment.
+	First error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("skipped %d corrupted records, first: %v", e.Skipped, e.First)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return ErrCorrupt
+}
+
+// Read calls fn for every record in dir with since <= time < until, oldest
+// segment first. Zero bounds are open. Returning ErrStop from fn ends the
+// scan without error. Records that cannot be decoded are skipped, and Read
+// then returns a *CorruptError after reading everything else.
+func Read(dir string, since, until time.Time, fn func(Record) error) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	var corrupt *CorruptError
+	skip := func(err error) {
+		if corrupt == nil {
+			corrupt = &CorruptError{First: err}
+		}
+		corrupt.Skipped++
+	}
+
+	for i, seg := range segments {
+		// Every record of a segment was written, and therefore started, before
+		// the next segment was opened. A segment may still hold records older
+		// than its own start, so until cannot prune the same way.
+		if !since.IsZero() && i+1 < len(segments) && !segments[i+1].start.After(since) {
+			continue
+		}
+		err := readSegment(seg, skip, func(rec Record) error {
+			if !since.IsZero() && rec.Time.Before(since) {
+				return nil
+			}
+			if !until.IsZero() && !rec.Time.Before(until) {
+				return nil
+			}
+			return fn(rec)
+		})
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if corrupt != nil {
+		return corrupt
+	}
+	return nil
+}
+
+var ErrStop = errors.New("stop reading result log")
+
+func readSegment(seg segment, skip func(error), fn func(Record) error) error {
+	file, err := os.Open(seg.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Rotated and compressed or pruned while we were listing.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	defer file.Close() //nolint
+
+	var r io.Reader = file
+	if seg.compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("open compressed segment %s: %w", seg.path, err)
+		}
+		defer gz.Close() //nolint
+		r = gz
+	}
+
+	var dec decoder
+	switch seg.format {
+	case FormatBinary:
+		bd, err := newBinaryDecoder(r)
+		if err != nil {
+			return fmt.Errorf("read segment %s: %w", seg.path, err)
+		}
+		dec = bd
+	default:
+		dec = newJSONDecoder(r)
+	}
+
+	for {
+		rec, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.Is(err, ErrCorrupt) {
+			skip(fmt.Errorf("segment %s: %w", seg.path, err))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read segment %s: %w", seg.path, err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadAll collects the records of Read into a slice.
+func ReadAll(dir string, since, until time.Time) ([]Record, error) {
+	var records []Record
+	err := Read(dir, since, until, func(rec Record) error {
+		records = append(records, rec)
+		return nil
+	})
+	return records, err
+}
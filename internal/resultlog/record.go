@@ -0,0 +1,271 @@
+package resultlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+type Format string
+
+const (
+	FormatJSON   Format = "jsonl"
+	FormatBinary Format = "bin"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "json", "jsonl":
+		return FormatJSON, nil
+	case "bin", "binary":
+		return FormatBinary, nil
+	}
+	return "", fmt.Errorf("unknown result log format %q", s)
+}
+
+// Record is the on-disk form of a schema.RequestResult.
+type Record struct {
+	Time         time.Time     `json:"time"`
+	URL          string        `json:"url"`
+	Duration     time.Duration `json:"duration"`
+	PayloadSize  int           `json:"payload_size"`
+	Status       int           `json:"status,omitempty"`
+	Success      bool          `json:"success"`
+	Error        string        `json:"error,omitempty"`
+	UpstreamDown string        `json:"upstream_down,omitempty"`
+	CheckType    string        `json:"check_type,omitempty"`
+	Attempts     int           `json:"attempts,omitempty"`
+	// Code is the response code of checks other than http.
+	Code string `json:"code,omitempty"`
+}
+
+func FromResult(r schema.RequestResult) Record {
+	rec := Record{
+		Time:         r.Timestamp,
+		URL:          r.URL,
+		Duration:     r.Duration,
+		PayloadSize:  r.PayloadSize,
+		Status:       r.Status,
+		Success:      r.Success,
+		UpstreamDown: r.UpstreamDown,
+		CheckType:    r.CheckType,
+		Attempts:     r.Attempts,
+		Code:         r.Code,
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if r.Error != nil {
+		rec.Error = r.Error.Error()
+	}
+	return rec
+}
+
+func (rec Record) Result() schema.RequestResult {
+	r := schema.RequestResult{
+		Timestamp:    rec.Time,
+		URL:          rec.URL,
+		Duration:     rec.Duration,
+		PayloadSize:  rec.PayloadSize,
+		Status:       rec.Status,
+		Success:      rec.Success,
+		UpstreamDown: rec.UpstreamDown,
+		CheckType:    rec.CheckType,
+		Attempts:     rec.Attempts,
+		Code:         rec.Code,
+	}
+	if rec.Error != "" {
+		r.Error = errors.New(rec.Error)
+	}
+	return r
+}
+
+type encoder interface {
+	Encode(rec Record) ([]byte, error)
+}
+
+type decoder interface {
+	Decode() (Record, error)
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(rec Record) ([]byte, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ErrCorrupt is returned for a record that cannot be decoded. Decoders can
+// go on with the next record after it.
+var ErrCorrupt = errors.New("corrupted record")
+
+type jsonDecoder struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newJSONDecoder(r io.Reader) *jsonDecoder {
+	return &jsonDecoder{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+func (d *jsonDecoder) Decode() (Record, error) {
+	var rec Record
+	line, err := d.r.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return rec, err
+	}
+	if len(line) == 0 {
+		return rec, io.EOF
+	}
+	d.line++
+	if jsonErr := json.Unmarshal(line, &rec); jsonErr != nil {
+		if err != nil {
+			// A torn final line without its newline, after a crash, ends the
+			// segment.
+			return rec, io.EOF
+		}
+		return rec, fmt.Errorf("%w: line %d: %v", ErrCorrupt, d.line, jsonErr)
+	}
+	return rec, nil
+}
+
+// binaryMagic starts every binary segment. The last byte is the format
+// version; segments of older versions can still be read.
+var binaryMagic = []byte("HSMR\x02")
+
+const binaryMagicPrefix = "HSMR"
+
+// binaryLayout is the number of varints and strings in a frame of each
+// format version.
+type binaryLayout struct {
+	ints, strs int
+}
+
+var binaryLayouts = map[byte]binaryLayout{
+	1: {ints: 4, strs: 3},
+	2: {ints: 5, strs: 5},
+}
+
+// Binary frames are a uvarint length followed by: time (unix nanos, varint),
+// duration (varint), payload size (varint), status (varint), attempts
+// (varint), flags (byte) and the url, error, upstream, check type and code
+// strings, each uvarint length prefixed. Version 1 frames lack attempts,
+// check type and code.
+type binaryEncoder struct{}
+
+const (
+	flagSuccess  = 1
+	maxFrameSize = 1 << 20
+)
+
+func (binaryEncoder) Encode(rec Record) ([]byte, error) {
+	body := make([]byte, 0, 64+len(rec.URL)+len(rec.Error))
+	body = binary.AppendVarint(body, rec.Time.UnixNano())
+	body = binary.AppendVarint(body, int64(rec.Duration))
+	body = binary.AppendVarint(body, int64(rec.PayloadSize))
+	body = binary.AppendVarint(body, int64(rec.Status))
+	body = binary.AppendVarint(body, int64(rec.Attempts))
+	var flags byte
+	if rec.Success {
+		flags |= flagSuccess
+	}
+	body = append(body, flags)
+	for _, s := range []string{rec.URL, rec.Error, rec.UpstreamDown, rec.CheckType, rec.Code} {
+		body = binary.AppendUvarint(body, uint64(len(s)))
+		body = append(body, s...)
+	}
+
+	frame := binary.AppendUvarint(make([]byte, 0, len(body)+binary.MaxVarintLen64), uint64(len(body)))
+	return append(frame, body...), nil
+}
+
+type binaryDecoder struct {
+	r      *bufio.Reader
+	layout binaryLayout
+}
+
+func newBinaryDecoder(r io.Reader) (*binaryDecoder, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		// An empty or torn header means the segment holds no records.
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return &binaryDecoder{r: br}, nil
+		}
+		return nil, err
+	}
+	if string(magic[:len(binaryMagicPrefix)]) != binaryMagicPrefix {
+		return nil, fmt.Errorf("not a binary result log segment")
+	}
+	version := magic[len(magic)-1]
+	layout, ok := binaryLayouts[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown binary result log version %d", version)
+	}
+	return &binaryDecoder{r: br, layout: layout}, nil
+}
+
+func (d *binaryDecoder) Decode() (Record, error) {
+	var rec Record
+	size, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return rec, io.EOF
+	}
+	if size > maxFrameSize {
+		// Frames cannot be found again after a broken length.
+		return rec, fmt.Errorf("corrupted binary frame length %d", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(d.r, body); err != nil {
+		// A torn final frame after a crash ends the segment.
+		return rec, io.EOF
+	}
+
+	ints := make([]int64, d.layout.ints)
+	for i := range ints {
+		v, n := binary.Varint(body)
+		if n <= 0 {
+			return rec, ErrCorrupt
+		}
+		ints[i] = v
+		body = body[n:]
+	}
+	if len(body) == 0 {
+		return rec, ErrCorrupt
+	}
+	flags := body[0]
+	body = body[1:]
+
+	strs := make([]string, d.layout.strs)
+	for i := range strs {
+		l, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) < l {
+			return rec, ErrCorrupt
+		}
+		strs[i] = string(body[n : n+int(l)])
+		body = body[n+int(l):]
+	}
+
+	rec.Time = time.Unix(0, ints[0])
+	rec.Duration = time.Duration(ints[1])
+	rec.PayloadSize = int(ints[2])
+	rec.Status = int(ints[3])
+	rec.Success = flags&flagSuccess != 0
+	rec.URL, rec.Error, rec.UpstreamDown = strs[0], strs[1], strs[2]
+	if len(ints) > 4 {
+		rec.Attempts = int(ints[4])
+	}
+	if len(strs) > 3 {
+		rec.CheckType, rec.Code = strs[3], strs[4]
+	}
+	return rec, nil
+}
@@ -0,0 +1,360 @@
+package resultlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+var epoch = time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+
+func openTestWriter(t *testing.T, opts Options, c *clock) *Writer {
+	t.Helper()
+
+	w, err := open(opts, c.Now)
+	require.NoError(t, err)
+	t.Cleanup(func() { w.Close() }) //nolint
+	return w
+}
+
+func record(i int, at time.Time) Record {
+	rec := Record{
+		Time:        at,
+		URL:         "https://example.com",
+		Duration:    time.Duration(i) * time.Millisecond,
+		PayloadSize: i,
+		Status:      200,
+		Success:     true,
+		CheckType:   "http",
+		Attempts:    1,
+	}
+	if i%2 == 1 {
+		rec.Status = 503
+		rec.Success = false
+		rec.Error = "service unavailable"
+		rec.UpstreamDown = "https://gateway.com"
+		rec.Attempts = 3
+	}
+	if i%3 == 2 {
+		rec.CheckType = "dns"
+		rec.Status = 0
+		rec.Code = "NXDOMAIN"
+	}
+	return rec
+}
+
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatBinary} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			// Test case for writing and reading back records in each format
+			// Verifies that every field survives encoding, rotation and gzip compression
+			dir := t.TempDir()
+			c := &clock{now: epoch}
+			w := openTestWriter(t, Options{Dir: dir, Format: format, MaxSegmentSize: 200}, c)
+
+			var written []Record
+			for i := 0; i < 20; i++ {
+				rec := record(i, epoch.Add(time.Duration(i)*time.Second))
+				written = append(written, rec)
+				c.now = rec.Time
+				require.NoError(t, w.Write(rec))
+			}
+			w.sealing.Wait()
+
+			names := files(t, dir)
+			assert.Greater(t, len(names), 2)
+			active := 0
+			for _, name := range names {
+				if !strings.HasSuffix(name, ".gz") {
+					active++
+				}
+			}
+			assert.Equal(t, 1, active)
+
+			read, err := ReadAll(dir, time.Time{}, time.Time{})
+			require.NoError(t, err)
+			require.Len(t, read, len(written))
+			for i := range written {
+				assert.True(t, written[i].Time.Equal(read[i].Time))
+				read[i].Time = written[i].Time
+				assert.Equal(t, written[i], read[i])
+			}
+		})
+	}
+}
+
+func TestWriter_TimeRotation(t *testing.T) {
+	// Test case for time based rotation
+	// Verifies that a new segment is started once the active one is older than the limit
+	dir := t.TempDir()
+	c := &clock{now: epoch}
+	w := openTestWriter(t, Options{Dir: dir, MaxSegmentAge: time.Hour}, c)
+
+	require.NoError(t, w.Write(record(0, c.now)))
+	c.now = c.now.Add(30 * time.Minute)
+	require.NoError(t, w.Write(record(2, c.now)))
+	assert.Len(t, files(t, dir), 1)
+
+	c.now = c.now.Add(31 * time.Minute)
+	require.NoError(t, w.Write(record(4, c.now)))
+	w.sealing.Wait()
+	assert.Len(t, files(t, dir), 2)
+}
+
+func TestWriter_Retention(t *testing.T) {
+	// Test case for pruning rotated segments
+	// Verifies that both the segment count and the age limits are enforced
+	dir := t.TempDir()
+	c := &clock{now: epoch}
+	w := openTestWriter(t, Options{Dir: dir, MaxSegmentAge: time.Hour, MaxSegments: 3, Retention: 4 * time.Hour}, c)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, w.Write(record(0, c.now)))
+		c.now = c.now.Add(time.Hour)
+	}
+	w.sealing.Wait()
+	// Three sealed segments plus the active one.
+	assert.Len(t, files(t, dir), 4)
+
+	w.opts.MaxSegments = 0
+	w.opts.Retention = 90 * time.Minute
+	c.now = c.now.Add(time.Hour)
+	require.NoError(t, w.Write(record(0, c.now)))
+	w.sealing.Wait()
+
+	records, err := ReadAll(dir, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	for _, rec := range records {
+		assert.False(t, rec.Time.Before(c.now.Add(-3*time.Hour)))
+	}
+}
+
+func TestWriter_CloseWaitsForSealing(t *testing.T) {
+	// Test case for closing right after rotations
+	// Verifies that every rotated segment is compressed once Close returns
+	dir := t.TempDir()
+	c := &clock{now: epoch}
+	w := openTestWriter(t, Options{Dir: dir, MaxSegmentAge: time.Minute}, c)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.Write(record(i, c.now)))
+		c.now = c.now.Add(time.Minute)
+	}
+	require.NoError(t, w.Close())
+
+	names := files(t, dir)
+	require.Len(t, names, 5)
+	for _, name := range names[:4] {
+		assert.True(t, strings.HasSuffix(name, ".gz"), name)
+	}
+}
+
+func TestOpen_SealsLeftoverSegment(t *testing.T) {
+	// Test case for restarting after an unclean shutdown
+	// Verifies that the previous active segment is compressed and its records stay readable
+	dir := t.TempDir()
+	c := &clock{now: epoch}
+	w := openTestWriter(t, Options{Dir: dir}, c)
+	require.NoError(t, w.Write(record(0, epoch)))
+
+	w2, err := open(Options{Dir: dir}, func() time.Time { return epoch.Add(time.Minute) })
+	require.NoError(t, err)
+	defer w2.Close() //nolint
+	require.NoError(t, w2.Write(record(2, epoch.Add(time.Minute))))
+
+	gz := 0
+	for _, name := range files(t, dir) {
+		if strings.HasSuffix(name, ".gz") {
+			gz++
+		}
+	}
+	assert.Equal(t, 1, gz)
+
+	records, err := ReadAll(dir, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestRead_TimeRangeAndStop(t *testing.T) {
+	// Test case for reading a time range
+	// Verifies that since is inclusive, until is exclusive and ErrStop ends the scan cleanly
+	dir := t.TempDir()
+	c := &clock{now: epoch}
+	w := openTestWriter(t, Options{Dir: dir, Format: FormatBinary, MaxSegmentAge: 10 * time.Minute}, c)
+
+	for i := 0; i < 60; i++ {
+		c.now = epoch.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, w.Write(record(i, c.now)))
+	}
+	w.sealing.Wait()
+
+	records, err := ReadAll(dir, epoch.Add(15*time.Minute), epoch.Add(25*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, records, 10)
+	assert.True(t, records[0].Time.Equal(epoch.Add(15*time.Minute)))
+	assert.True(t, records[9].Time.Equal(epoch.Add(24*time.Minute)))
+
+	count := 0
+	err = Read(dir, time.Time{}, time.Time{}, func(Record) error {
+		count++
+		if count == 5 {
+			return ErrStop
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
+
+func TestRead_TornTail(t *testing.T) {
+	// Test case for a segment cut off in the middle of a record
+	// Verifies that the complete records are returned and the partial one is ignored
+	for _, format := range []Format{FormatJSON, FormatBinary} {
+		dir := t.TempDir()
+		c := &clock{now: epoch}
+		w := openTestWriter(t, Options{Dir: dir, Format: format}, c)
+		require.NoError(t, w.Write(record(0, epoch)))
+		require.NoError(t, w.Write(record(1, epoch)))
+		path := w.file.Name()
+		require.NoError(t, w.Close())
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(path, info.Size()-3))
+
+		records, err := ReadAll(dir, time.Time{}, time.Time{})
+		require.NoError(t, err, format)
+		assert.Len(t, records, 1, format)
+	}
+}
+
+func TestRead_CorruptRecord(t *testing.T) {
+	// Test case for a damaged record between good ones
+	// Verifies that the damaged record is skipped and reported without losing the records after it
+	dir := t.TempDir()
+	var jsonSegment []byte
+	for _, rec := range []Record{record(0, epoch), record(1, epoch)} {
+		line, err := jsonEncoder{}.Encode(rec)
+		require.NoError(t, err)
+		jsonSegment = append(jsonSegment, line...)
+		if len(jsonSegment) == len(line) {
+			jsonSegment = append(jsonSegment, "{garbage\n"...)
+		}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, segmentName(epoch, FormatJSON)), jsonSegment, 0o644))
+
+	binarySegment := append([]byte{}, binaryMagic...)
+	for _, rec := range []Record{record(2, epoch), record(3, epoch)} {
+		frame, err := binaryEncoder{}.Encode(rec)
+		require.NoError(t, err)
+		binarySegment = append(binarySegment, frame...)
+		if len(binarySegment) == len(binaryMagic)+len(frame) {
+			binarySegment = append(binarySegment, 3, 0xff, 0xff, 0xff)
+		}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, segmentName(epoch.Add(time.Hour), FormatBinary)), binarySegment, 0o644))
+
+	records, err := ReadAll(dir, time.Time{}, time.Time{})
+	var corrupt *CorruptError
+	require.True(t, errors.As(err, &corrupt), "got %v", err)
+	assert.ErrorIs(t, err, ErrCorrupt)
+	assert.Equal(t, 2, corrupt.Skipped)
+	assert.Contains(t, corrupt.First.Error(), "line 2")
+	require.Len(t, records, 4)
+	for i, rec := range records {
+		assert.Equal(t, i, rec.PayloadSize)
+	}
+}
+
+func TestRead_BinaryVersion1(t *testing.T) {
+	// Test case for a binary segment written before attempts, check types and codes were recorded
+	// Verifies that its records are still read, without the newer fields
+	body := binary.AppendVarint(nil, epoch.UnixNano())
+	body = binary.AppendVarint(body, int64(time.Second))
+	body = binary.AppendVarint(body, 42)
+	body = binary.AppendVarint(body, 200)
+	body = append(body, flagSuccess)
+	for _, s := range []string{"https://example.com", "", ""} {
+		body = binary.AppendUvarint(body, uint64(len(s)))
+		body = append(body, s...)
+	}
+	segment := append([]byte("HSMR\x01"), binary.AppendUvarint(nil, uint64(len(body)))...)
+	segment = append(segment, body...)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, segmentName(epoch, FormatBinary)), segment, 0o644))
+
+	records, err := ReadAll(dir, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "https://example.com", records[0].URL)
+	assert.Equal(t, 42, records[0].PayloadSize)
+	assert.True(t, records[0].Success)
+	assert.Zero(t, records[0].Attempts)
+	assert.Empty(t, records[0].CheckType)
+}
+
+func TestRecord_Result(t *testing.T) {
+	// Test case for converting between results and records
+	// Verifies that errors are stored as text and restored as errors, and
+	// that attempts, check types and codes are kept
+	result := schema.RequestResult{
+		Timestamp: epoch,
+		URL:       "grpc://example.com:50051",
+		CheckType: "grpc",
+		Duration:  time.Second,
+		Attempts:  2,
+		Code:      "NOT_SERVING",
+		Error:     errors.New("boom"),
+	}
+
+	rec := FromResult(result)
+	assert.Equal(t, "boom", rec.Error)
+	assert.EqualError(t, rec.Result().Error, "boom")
+	assert.Equal(t, result.Duration, rec.Result().Duration)
+	assert.Equal(t, "grpc", rec.Result().CheckType)
+	assert.Equal(t, 2, rec.Result().Attempts)
+	assert.Equal(t, "NOT_SERVING", rec.Result().Code)
+}
+
+func TestParseSegmentName(t *testing.T) {
+	seg, ok := parseSegmentName(segmentName(epoch, FormatBinary) + ".gz")
+	require.True(t, ok)
+	assert.True(t, seg.compressed)
+	assert.Equal(t, FormatBinary, seg.format)
+	assert.True(t, seg.start.Equal(epoch))
+
+	_, ok = parseSegmentName("state.json")
+	assert.False(t, ok)
+	_, ok = parseSegmentName(filepath.Base(segmentName(epoch, "txt")))
+	assert.False(t, ok)
+}
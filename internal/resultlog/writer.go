@@ -0,0 +1,274 @@
+package resultlog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+type Options struct {
+	Dir    string
+	Format Format
+	// MaxSegmentSize and MaxSegmentAge trigger rotation of the active segment.
+	// Zero disables the respective limit.
+	MaxSegmentSize int64
+	MaxSegmentAge  time.Duration
+	// MaxSegments and Retention bound the rotated, compressed segments kept
+	// on disk. Zero disables the respective limit.
+	MaxSegments int
+	Retention   time.Duration
+}
+
+// Writer appends results to the active segment of a log directory. Rotated
+// segments are gzipped and pruned according to the retention options, in the
+// background so that writes do not wait for the compression.
+type Writer struct {
+	mutex   sync.Mutex
+	opts    Options
+	encoder encoder
+	file    *os.File
+	size    int64
+	opened  time.Time
+	now     func() time.Time
+
+	// sealing tracks the background compressions, which sealMutex runs one
+	// at a time.
+	sealing   sync.WaitGroup
+	sealMutex sync.Mutex
+}
+
+func Open(opts Options) (*Writer, error) {
+	return open(opts, time.Now)
+}
+
+func open(opts Options, now func() time.Time) (*Writer, error) {
+	if opts.Format == "" {
+		opts.Format = FormatJSON
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create result log directory: %w", err)
+	}
+
+	w := &Writer{opts: opts, now: now}
+	switch opts.Format {
+	case FormatJSON:
+		w.encoder = jsonEncoder{}
+	case FormatBinary:
+		w.encoder = binaryEncoder{}
+	default:
+		return nil, fmt.Errorf("unknown result log format %q", opts.Format)
+	}
+
+	// Segments left active by a previous run are sealed before starting anew.
+	segments, err := listSegments(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range segments {
+		if !seg.compressed {
+			if err := compressSegment(seg.path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := w.prune(now()); err != nil {
+		return nil, err
+	}
+	if err := w.openSegment(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Handle records a probe result. It matches the monitor result handler
+// signature and logs write failures instead of returning them.
+func (w *Writer) Handle(result schema.RequestResult) {
+	if err := w.Write(FromResult(result)); err != nil {
+		log.WithError(err).Error("failed to write result log")
+	}
+}
+
+func (w *Writer) Write(rec Record) error {
+	data, err := w.encoder.Encode(rec)
+	if err != nil {
+		return fmt.Errorf("encode result: %w", err)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return fmt.Errorf("result log is closed")
+	}
+	if w.shouldRotate(int64(len(data))) {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write result: %w", err)
+	}
+	return nil
+}
+
+// Close closes the active segment and waits for rotated segments to be
+// compressed.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mutex.Unlock()
+
+	w.sealing.Wait()
+	return err
+}
+
+func (w *Writer) shouldRotate(next int64) bool {
+	if w.size <= int64(w.headerSize()) {
+		return false
+	}
+	if w.opts.MaxSegmentSize > 0 && w.size+next > w.opts.MaxSegmentSize {
+		return true
+	}
+	return w.opts.MaxSegmentAge > 0 && w.now().Sub(w.opened) >= w.opts.MaxSegmentAge
+}
+
+func (w *Writer) headerSize() int {
+	if w.opts.Format == FormatBinary {
+		return len(binaryMagic)
+	}
+	return 0
+}
+
+// rotate closes the active segment and opens a new one. The closed segment
+// is sealed in the background; until then it is read uncompressed.
+func (w *Writer) rotate() error {
+	path := w.file.Name()
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close result log segment: %w", err)
+	}
+	w.file = nil
+
+	now := w.now()
+	w.sealing.Add(1)
+	go func() {
+		defer w.sealing.Done()
+		if err := w.seal(path, now); err != nil {
+			log.WithError(err).Error("failed to seal result log segment")
+		}
+	}()
+	return w.openSegment()
+}
+
+// seal compresses a rotated segment and prunes the expired ones as of now.
+func (w *Writer) seal(path string, now time.Time) error {
+	w.sealMutex.Lock()
+	defer w.sealMutex.Unlock()
+
+	if err := compressSegment(path); err != nil {
+		return err
+	}
+	return w.prune(now)
+}
+
+func (w *Writer) openSegment() error {
+	now := w.now()
+	path := filepath.Join(w.opts.Dir, segmentName(now, w.opts.Format))
+	for exists(path) || exists(path+".gz") {
+		now = now.Add(time.Nanosecond)
+		path = filepath.Join(w.opts.Dir, segmentName(now, w.opts.Format))
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open result log segment: %w", err)
+	}
+	w.file = file
+	w.size = 0
+	w.opened = now
+
+	if w.opts.Format == FormatBinary {
+		n, err := file.Write(binaryMagic)
+		w.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("write result log header: %w", err)
+		}
+	}
+	return nil
+}
+
+// prune removes the oldest compressed segments beyond MaxSegments and every
+// compressed segment older than Retention.
+func (w *Writer) prune(now time.Time) error {
+	segments, err := listSegments(w.opts.Dir)
+	if err != nil {
+		return err
+	}
+
+	var sealed []segment
+	for _, seg := range segments {
+		if seg.compressed {
+			sealed = append(sealed, seg)
+		}
+	}
+
+	cutoff := now.Add(-w.opts.Retention)
+	for i, seg := range sealed {
+		tooMany := w.opts.MaxSegments > 0 && len(sealed)-i > w.opts.MaxSegments
+		tooOld := w.opts.Retention > 0 && seg.start.Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(seg.path); err != nil {
+				return fmt.Errorf("remove expired segment: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func compressSegment(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open segment for compression: %w", err)
+	}
+	defer src.Close() //nolint
+
+	dst, err := os.Create(path + ".gz.tmp")
+	if err != nil {
+		return fmt.Errorf("create compressed segment: %w", err)
+	}
+	defer os.Remove(dst.Name()) //nolint
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close() //nolint
+		return fmt.Errorf("compress segment: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close() //nolint
+		return fmt.Errorf("compress segment: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("compress segment: %w", err)
+	}
+	if err := os.Rename(dst.Name(), path+".gz"); err != nil {
+		return fmt.Errorf("store compressed segment: %w", err)
+	}
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
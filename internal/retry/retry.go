@@ -0,0 +1,157 @@
+package retry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+)
+
+type Category string
+
+const (
+	CategoryTimeout    Category = "timeout"
+	CategoryDNS        Category = "dns"
+	CategoryConnection Category = "connection"
+	CategoryTLS        Category = "tls"
+	CategoryOther      Category = "other"
+)
+
+var defaultRetryOn = []string{string(CategoryTimeout), string(CategoryDNS), string(CategoryConnection)}
+
+// Classify maps a probe error onto a retry category.
+func Classify(err error) Category {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return CategoryTimeout
+		}
+		return CategoryDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return CategoryTimeout
+	case errors.As(err, &recordErr), errors.As(err, &certErr), errors.As(err, &unknownAuthority),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		return CategoryTLS
+	case errors.As(err, &opErr), errors.Is(err, net.ErrClosed), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return CategoryConnection
+	}
+	return CategoryOther
+}
+
+// Policy decides whether a failed probe is attempted again and how long to
+// back off in between. The zero Policy never retries.
+type Policy struct {
+	maxAttempts int
+	categories  map[Category]bool
+	statuses    map[int]bool
+	classes     map[int]bool
+	initial     time.Duration
+	max         time.Duration
+	multiplier  float64
+	jitter      float64
+}
+
+func New(cfg config.RetryPolicy) (Policy, error) {
+	p := Policy{
+		maxAttempts: cfg.MaxAttempts,
+		categories:  make(map[Category]bool),
+		statuses:    make(map[int]bool),
+		classes:     make(map[int]bool),
+		initial:     time.Duration(cfg.InitialBackoff),
+		max:         time.Duration(cfg.MaxBackoff),
+		multiplier:  cfg.Multiplier,
+		jitter:      cfg.Jitter,
+	}
+	if p.initial <= 0 {
+		p.initial = 200 * time.Millisecond
+	}
+	if p.max <= 0 {
+		p.max = 5 * time.Second
+	}
+	if p.multiplier < 1 {
+		p.multiplier = 2
+	}
+	if p.jitter < 0 || p.jitter > 1 {
+		return Policy{}, fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+
+	retryOn := cfg.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOn
+	}
+	for _, item := range retryOn {
+		switch {
+		case item == string(CategoryTimeout), item == string(CategoryDNS), item == string(CategoryConnection),
+			item == string(CategoryTLS), item == string(CategoryOther):
+			p.categories[Category(item)] = true
+		case len(item) == 3 && strings.HasSuffix(item, "xx") && item[0] >= '1' && item[0] <= '5':
+			p.classes[int(item[0]-'0')] = true
+		default:
+			code, err := strconv.Atoi(item)
+			if err != nil || code < 100 || code > 599 {
+				return Policy{}, fmt.Errorf("unknown retry condition %q", item)
+			}
+			p.statuses[code] = true
+		}
+	}
+	return p, nil
+}
+
+func (p Policy) MaxAttempts() int {
+	if p.maxAttempts < 1 {
+		return 1
+	}
+	return p.maxAttempts
+}
+
+// ShouldRetry reports whether a failed result is worth another attempt. A
+// result with a status, even one that also failed with an error such as a
+// broken body, is retried on a listed status or class; otherwise on the
+// category of its error.
+func (p Policy) ShouldRetry(result schema.RequestResult) bool {
+	if result.Success {
+		return false
+	}
+	if result.Status != 0 && (p.statuses[result.Status] || p.classes[result.Status/100]) {
+		return true
+	}
+	if result.Error != nil {
+		return p.categories[Classify(result.Error)]
+	}
+	return false
+}
+
+// Backoff returns the wait after the given failed attempt (starting at 1):
+// exponential growth capped at the maximum, spread by +/- jitter.
+func (p Policy) Backoff(attempt int) time.Duration {
+	d := float64(p.initial) * math.Pow(p.multiplier, float64(attempt-1))
+	if d > float64(p.max) {
+		d = float64(p.max)
+	}
+	if p.jitter > 0 {
+		d *= 1 - p.jitter + 2*p.jitter*rand.Float64()
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,150 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/config"
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://example.com", Err: err}
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected Category
+	}{
+		// Test case for a request that ran into its deadline
+		// Verifies that deadline errors are classified as timeouts
+		{name: "deadline", err: wrap(context.DeadlineExceeded), expected: CategoryTimeout},
+		// Test case for a name that does not resolve
+		// Verifies that DNS errors get their own category
+		{name: "dns", err: wrap(&net.DNSError{Err: "no such host", Name: "x"}), expected: CategoryDNS},
+		// Test case for a DNS lookup that timed out
+		// Verifies that DNS timeouts count as timeouts
+		{name: "dns timeout", err: wrap(&net.DNSError{Err: "timeout", IsTimeout: true}), expected: CategoryTimeout},
+		// Test case for a refused connection
+		// Verifies that dial errors are classified as connection errors
+		{name: "refused", err: wrap(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), expected: CategoryConnection},
+		// Test case for a connection closed mid response
+		// Verifies that unexpected EOF is classified as a connection error
+		{name: "eof", err: wrap(io.ErrUnexpectedEOF), expected: CategoryConnection},
+		// Test case for an unrelated error
+		// Verifies that unknown errors fall into the other category
+		{name: "other", err: errors.New("boom"), expected: CategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Classify(tt.err))
+		})
+	}
+}
+
+func TestPolicy_ShouldRetry(t *testing.T) {
+	p, err := New(config.RetryPolicy{MaxAttempts: 3, RetryOn: []string{"timeout", "5xx", "429"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		result   schema.RequestResult
+		expected bool
+	}{
+		// Test case for a successful probe
+		// Verifies that successes are never retried
+		{name: "success", result: schema.RequestResult{Success: true, Status: 200}, expected: false},
+		// Test case for a configured error category
+		// Verifies that timeouts are retried when listed
+		{name: "timeout", result: schema.RequestResult{Error: context.DeadlineExceeded}, expected: true},
+		// Test case for an error category that is not configured
+		// Verifies that unlisted categories are not retried
+		{name: "dns", result: schema.RequestResult{Error: &net.DNSError{Err: "no such host"}}, expected: false},
+		// Test case for a status class
+		// Verifies that 5xx matches every server error
+		{name: "503", result: schema.RequestResult{Status: 503}, expected: true},
+		// Test case for an exact status code
+		// Verifies that listed codes are retried
+		{name: "429", result: schema.RequestResult{Status: 429}, expected: true},
+		// Test case for an unlisted status code
+		// Verifies that other client errors are not retried
+		{name: "404", result: schema.RequestResult{Status: 404}, expected: false},
+		// Test case for a retryable status whose body could not be read
+		// Verifies that the status counts even though the result has an error
+		{name: "503 with error", result: schema.RequestResult{Status: 503, Error: io.ErrUnexpectedEOF}, expected: true},
+		// Test case for an unlisted status whose body read timed out
+		// Verifies that the error category applies when the status does not match
+		{name: "200 with timeout", result: schema.RequestResult{Status: 200, Error: context.DeadlineExceeded}, expected: true},
+		// Test case for an unlisted status with an unlisted error category
+		// Verifies that neither the status nor the error triggers a retry
+		{name: "404 with error", result: schema.RequestResult{Status: 404, Error: io.ErrUnexpectedEOF}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, p.ShouldRetry(tt.result))
+		})
+	}
+}
+
+func TestPolicy_Defaults(t *testing.T) {
+	// Test case for a policy with only an attempt count
+	// Verifies that network errors are retried and status codes are not
+	p, err := New(config.RetryPolicy{MaxAttempts: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, p.MaxAttempts())
+	assert.True(t, p.ShouldRetry(schema.RequestResult{Error: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}))
+	assert.False(t, p.ShouldRetry(schema.RequestResult{Status: 500}))
+
+	var zero Policy
+	assert.Equal(t, 1, zero.MaxAttempts())
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	// Test case for exponential backoff with a cap and jitter
+	// Verifies that waits grow by the multiplier, stop at the maximum and stay within the jitter band
+	p, err := New(config.RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: config.Duration(100 * time.Millisecond),
+		MaxBackoff:     config.Duration(time.Second),
+		Multiplier:     2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 100*time.Millisecond, p.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, p.Backoff(2))
+	assert.Equal(t, 800*time.Millisecond, p.Backoff(4))
+	assert.Equal(t, time.Second, p.Backoff(8))
+
+	p, err = New(config.RetryPolicy{MaxAttempts: 3, InitialBackoff: config.Duration(100 * time.Millisecond), Jitter: 0.5})
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 150*time.Millisecond)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, cfg := range []config.RetryPolicy{
+		{RetryOn: []string{"sometimes"}},
+		{RetryOn: []string{"6xx"}},
+		{RetryOn: []string{"999"}},
+		{Jitter: 2},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, fmt.Sprint(cfg))
+	}
+}
@@ -0,0 +1,280 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+type Job struct {
+	Key      string
+	Interval time.Duration
+	// Delay postpones the first run, which is otherwise due immediately.
+	Delay time.Duration
+	// Run receives the scheduling lag: how long the run waited past its due
+	// time for a free worker. It returns the interval until the next run, or
+	// zero to keep the job's Interval.
+	Run func(ctx context.Context, lag time.Duration) time.Duration
+	// Admit, when set, is asked by the worker picking up a due run whether
+	// it may start. It returns zero and a nil channel to run now. Otherwise
+	// the run is due again after the delay, or once the channel is closed; a
+	// run kept waiting does not hold a worker.
+	Admit func() (time.Duration, <-chan struct{})
+}
+
+type entry struct {
+	job  Job
+	due  time.Time
+	next time.Duration
+	// deferred marks a run that was not admitted, to be due again after
+	// postpone or once ready is closed.
+	deferred bool
+	postpone time.Duration
+	ready    <-chan struct{}
+	index    int
+}
+
+type queue []*entry
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *queue) Pop() any {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	e.index = -1
+	return e
+}
+
+type LagStats struct {
+	Runs  int
+	Total time.Duration
+	Max   time.Duration
+	Last  time.Duration
+}
+
+func (s LagStats) Avg() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Runs)
+}
+
+// Scheduler keeps every job in a priority queue ordered by due time and hands
+// due jobs to a fixed pool of workers. A job is requeued only after its run
+// finishes, so runs of the same job never overlap.
+type Scheduler struct {
+	workers int
+	jitter  time.Duration
+
+	mutex sync.Mutex
+	queue queue
+	lag   LagStats
+	wake  chan struct{}
+	now   func() time.Time
+}
+
+type Option func(*Scheduler)
+
+// WithJitter shifts every rescheduled run by a random offset in
+// [-jitter, +jitter] to keep runs from drifting into lockstep.
+func WithJitter(jitter time.Duration) Option {
+	return func(s *Scheduler) {
+		s.jitter = jitter
+	}
+}
+
+func New(workers int, opts ...Option) *Scheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	s := &Scheduler{
+		workers: workers,
+		wake:    make(chan struct{}, 1),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Scheduler) Add(job Job) {
+	s.mutex.Lock()
+	heap.Push(&s.queue, &entry{job: job, due: s.now().Add(job.Delay)})
+	s.mutex.Unlock()
+	s.notify()
+}
+
+func (s *Scheduler) Lag() LagStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lag
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run dispatches jobs until ctx is done and then waits for running jobs to
+// return.
+func (s *Scheduler) Run(ctx context.Context) {
+	work := make(chan *entry)
+	finished := make(chan *entry, s.workers)
+
+	var wg sync.WaitGroup
+	wg.Add(s.workers)
+	for i := 0; i < s.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for e := range work {
+				if e.job.Admit != nil {
+					if wait, ready := e.job.Admit(); wait > 0 || ready != nil {
+						e.deferred, e.postpone, e.ready = true, wait, ready
+						finished <- e
+						continue
+					}
+				}
+				lag := s.now().Sub(e.due)
+				if lag < 0 {
+					lag = 0
+				}
+				s.recordLag(lag)
+				e.next = e.job.Run(ctx, lag)
+				finished <- e
+			}
+		}()
+	}
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	var pending *entry
+	for {
+		var timerC <-chan time.Time
+		if pending == nil {
+			pending, timerC = s.nextDue(timer)
+		}
+
+		var workC chan<- *entry
+		if pending != nil {
+			workC = work
+		}
+
+		select {
+		case workC <- pending:
+			pending = nil
+		case e := <-finished:
+			if e.ready != nil {
+				// Parked outside the queue until admission is possible again.
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					select {
+					case <-e.ready:
+					case <-ctx.Done():
+					}
+					e.ready = nil
+					finished <- e
+				}()
+				continue
+			}
+			s.reschedule(e)
+		case <-timerC:
+		case <-s.wake:
+		case <-ctx.Done():
+			close(work)
+			go func() {
+				wg.Wait()
+				close(finished)
+			}()
+			// Keep the queue intact so that no job is lost from it.
+			if pending != nil {
+				s.mutex.Lock()
+				heap.Push(&s.queue, pending)
+				s.mutex.Unlock()
+			}
+			for e := range finished {
+				s.reschedule(e)
+			}
+			return
+		}
+	}
+}
+
+// nextDue pops the earliest job when it is due, or arms the timer for it.
+func (s *Scheduler) nextDue(timer *time.Timer) (*entry, <-chan time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, nil
+	}
+	wait := s.queue[0].due.Sub(s.now())
+	if wait <= 0 {
+		return heap.Pop(&s.queue).(*entry), nil
+	}
+	timer.Reset(wait)
+	return nil, timer.C
+}
+
+func (s *Scheduler) reschedule(e *entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// A run that was not admitted is due again after its delay, which is not
+	// counted as scheduling lag.
+	if e.deferred {
+		e.due = s.now().Add(e.postpone)
+		e.deferred, e.postpone, e.ready = false, 0, nil
+		heap.Push(&s.queue, e)
+		return
+	}
+
+	interval := e.next
+	if interval <= 0 {
+		interval = e.job.Interval
+	}
+	next := e.due.Add(interval)
+	if s.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int64N(int64(2*s.jitter)+1)) - s.jitter)
+	}
+	// A run that overran its interval is not followed by a burst of catch-up
+	// runs; the next one is simply due now.
+	if now := s.now(); next.Before(now) {
+		next = now
+	}
+	e.due = next
+	heap.Push(&s.queue, e)
+}
+
+func (s *Scheduler) recordLag(lag time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lag.Runs++
+	s.lag.Total += lag
+	s.lag.Last = lag
+	if lag > s.lag.Max {
+		s.lag.Max = lag
+	}
+}
@@ -0,0 +1,366 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_RunsJobsRepeatedly(t *testing.T) {
+	t.Parallel()
+
+	// Test case for several jobs sharing a small worker pool
+	// Verifies that every job keeps running and the pool bound is never exceeded
+	const workers = 2
+	s := New(workers)
+
+	var running, maxRunning int32
+	counts := make([]int32, 5)
+	for i := range counts {
+		i := i
+		s.Add(Job{
+			Key:      fmt.Sprint(i),
+			Interval: 5 * time.Millisecond,
+			Run: func(ctx context.Context, lag time.Duration) time.Duration {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&counts[i], 1)
+				atomic.AddInt32(&running, -1)
+				return 0
+			},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(workers))
+	for i := range counts {
+		assert.GreaterOrEqual(t, atomic.LoadInt32(&counts[i]), int32(5), "job %d", i)
+	}
+}
+
+func TestScheduler_NoOverlappingRuns(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a job slower than its interval
+	// Verifies that a job never runs concurrently with itself and does not queue catch-up runs
+	s := New(4)
+
+	var running, overlaps, runs int32
+	s.Add(Job{
+		Key:      "slow",
+		Interval: time.Millisecond,
+		Run: func(ctx context.Context, lag time.Duration) time.Duration {
+			if atomic.AddInt32(&running, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&runs, 1)
+			atomic.AddInt32(&running, -1)
+			return 0
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.Zero(t, atomic.LoadInt32(&overlaps))
+	assert.LessOrEqual(t, atomic.LoadInt32(&runs), int32(11))
+}
+
+func TestScheduler_Delay(t *testing.T) {
+	t.Parallel()
+
+	// Test case for staggered first runs
+	// Verifies that a job does not run before its initial delay
+	s := New(1)
+	start := time.Now()
+	first := make(chan time.Duration, 1)
+	s.Add(Job{
+		Key:      "delayed",
+		Interval: time.Hour,
+		Delay:    50 * time.Millisecond,
+		Run: func(ctx context.Context, lag time.Duration) time.Duration {
+			first <- time.Since(start)
+			return 0
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.Run(ctx)
+	defer cancel()
+
+	select {
+	case elapsed := <-first:
+		assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("delayed job never ran")
+	}
+}
+
+func TestScheduler_RecordsLag(t *testing.T) {
+	t.Parallel()
+
+	// Test case for more due jobs than workers
+	// Verifies that the time spent waiting for a worker is reported as lag
+	s := New(1)
+	var mutex sync.Mutex
+	var lags []time.Duration
+	for i := 0; i < 3; i++ {
+		s.Add(Job{
+			Key:      fmt.Sprint(i),
+			Interval: time.Hour,
+			Run: func(ctx context.Context, lag time.Duration) time.Duration {
+				mutex.Lock()
+				lags = append(lags, lag)
+				mutex.Unlock()
+				time.Sleep(20 * time.Millisecond)
+				return 0
+			},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Len(t, lags, 3)
+	assert.GreaterOrEqual(t, lags[2], 40*time.Millisecond)
+
+	stats := s.Lag()
+	assert.Equal(t, 3, stats.Runs)
+	assert.Equal(t, lags[2], stats.Max)
+	assert.Greater(t, stats.Avg(), time.Duration(0))
+}
+
+func TestScheduler_StopWaitsForRunningJobs(t *testing.T) {
+	t.Parallel()
+
+	// Test case for cancelling the scheduler while jobs are running
+	// Verifies that Run returns only after in-flight runs have finished
+	s := New(3)
+	var finished int32
+	started := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		s.Add(Job{
+			Key:      fmt.Sprint(i),
+			Interval: time.Hour,
+			Run: func(ctx context.Context, lag time.Duration) time.Duration {
+				started <- struct{}{}
+				time.Sleep(30 * time.Millisecond)
+				atomic.AddInt32(&finished, 1)
+				return 0
+			},
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	cancel()
+	<-done
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&finished))
+	assert.Len(t, s.queue, 3)
+}
+
+// BenchmarkScheduler_10kTargets dispatches no-op probes for 10,000 targets and
+// reports the cost per dispatch, the scheduling lag and the live heap, which
+// stay flat as b.N grows.
+func BenchmarkScheduler_10kTargets(b *testing.B) {
+	const targets = 10000
+
+	s := New(64, WithJitter(time.Millisecond))
+	var runs int64
+	done := make(chan struct{})
+	for i := 0; i < targets; i++ {
+		s.Add(Job{
+			Key:      fmt.Sprint(i),
+			Interval: 50 * time.Millisecond,
+			Delay:    50 * time.Millisecond * time.Duration(i) / targets,
+			Run: func(ctx context.Context, lag time.Duration) time.Duration {
+				if atomic.AddInt64(&runs, 1) == int64(b.N) {
+					close(done)
+				}
+				return 0
+			},
+		})
+	}
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.ReportAllocs()
+	b.ResetTimer()
+	go s.Run(ctx)
+	<-done
+	b.StopTimer()
+	cancel()
+
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	lag := s.Lag()
+	b.ReportMetric(float64(lag.Avg().Microseconds()), "avg-lag-µs")
+	b.ReportMetric(float64(lag.Max.Microseconds()), "max-lag-µs")
+	b.ReportMetric(float64(after.HeapInuse)/(1<<20), "heap-MB")
+}
+
+func BenchmarkQueue_PushPop10k(b *testing.B) {
+	var q queue
+	now := time.Now()
+	for i := 0; i < 10000; i++ {
+		heap.Push(&q, &entry{due: now.Add(time.Duration(i) * time.Microsecond)})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := heap.Pop(&q).(*entry)
+		e.due = e.due.Add(10 * time.Millisecond)
+		heap.Push(&q, e)
+	}
+}
+
+func TestScheduler_RunReturnsNextInterval(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a job that shortens its own interval after the first run
+	// Verifies that the returned interval replaces the job's Interval
+	s := New(1)
+	runs := make(chan time.Time, 2)
+	s.Add(Job{
+		Key:      "adaptive",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, lag time.Duration) time.Duration {
+			select {
+			case runs <- time.Now():
+			default:
+			}
+			return 10 * time.Millisecond
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.Run(ctx)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("job was not rescheduled with the returned interval")
+		}
+	}
+}
+
+func TestScheduler_Admit(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a job that is not admitted while another one is due
+	// Verifies that the waiting job does not hold the only worker and runs once admitted, without lag
+	s := New(1)
+	start := time.Now()
+	var admits, blockedRuns, freeRuns int32
+	lags := make(chan time.Duration, 1)
+	s.Add(Job{
+		Key:      "blocked",
+		Interval: time.Hour,
+		Admit: func() (time.Duration, <-chan struct{}) {
+			if atomic.AddInt32(&admits, 1) < 5 {
+				return 10 * time.Millisecond, nil
+			}
+			return 0, nil
+		},
+		Run: func(ctx context.Context, lag time.Duration) time.Duration {
+			atomic.AddInt32(&blockedRuns, 1)
+			lags <- lag
+			return 0
+		},
+	})
+	s.Add(Job{
+		Key:      "free",
+		Interval: 5 * time.Millisecond,
+		Run: func(ctx context.Context, lag time.Duration) time.Duration {
+			atomic.AddInt32(&freeRuns, 1)
+			return 0
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	assert.Equal(t, int32(5), atomic.LoadInt32(&admits))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&blockedRuns))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&freeRuns), int32(5))
+	assert.Less(t, <-lags, 10*time.Millisecond)
+	assert.Equal(t, int(atomic.LoadInt32(&freeRuns))+1, s.Lag().Runs)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestScheduler_AdmitReady(t *testing.T) {
+	t.Parallel()
+
+	// Test case for a job waiting on a channel to be admitted
+	// Verifies that the job is parked without being asked again and runs once the channel is closed
+	s := New(1)
+	ready := make(chan struct{})
+	var admits int32
+	ran := make(chan time.Duration, 1)
+	s.Add(Job{
+		Key:      "parked",
+		Interval: time.Hour,
+		Admit: func() (time.Duration, <-chan struct{}) {
+			if atomic.AddInt32(&admits, 1) == 1 {
+				return 0, ready
+			}
+			return 0, nil
+		},
+		Run: func(ctx context.Context, lag time.Duration) time.Duration {
+			ran <- lag
+			return 0
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&admits))
+		close(ready)
+	}()
+	s.Run(ctx)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&admits))
+	assert.Len(t, ran, 1)
+	assert.Less(t, <-ran, 10*time.Millisecond)
+	assert.Equal(t, 1, s.Lag().Runs)
+}
@@ -2,13 +2,120 @@ package schema
 
 import "time"
 
+// Connection kinds of a probe, as reported by the HTTP transport.
+const (
+	ConnectionNew    = "new"
+	ConnectionReused = "reused"
+)
+
 type RequestResult struct {
-	URL         string
+	Timestamp time.Time
+	URL       string
+	// CheckType names the kind of check that produced the result, such as
+	// http.
+	CheckType   string
 	Duration    time.Duration
 	PayloadSize int
 	Status      int
 	Success     bool
 	Error       error
+	// UpstreamDown names the dependency that was down when this probe failed.
+	UpstreamDown string
+	// SchedulingLag is how long the probe waited past its due time.
+	SchedulingLag time.Duration
+	// Attempts is how many tries the probe took, including retries.
+	Attempts int
+	// LimiterDelay is how long the probe waited for its host's limits. It is
+	// not part of Duration.
+	LimiterDelay time.Duration
+	// BodyTooLarge is set when reading the body was aborted at the target's
+	// maximum body size.
+	BodyTooLarge bool
+	// Connection is ConnectionNew or ConnectionReused, or empty when the
+	// transport did not report it. ConnSetup is how long a new connection
+	// took to set up, including DNS, TCP and TLS.
+	Connection string
+	ConnSetup  time.Duration
+	// DNS is set by dns checks.
+	DNS *DNSAnswer
+	// Cert describes the peer certificate of https and tls checks.
+	Cert *CertInfo
+	// Degraded is set on probes that succeeded with a warning, such as a
+	// certificate close to expiry.
+	Degraded bool
+	// TLSAudit is set by tls-audit checks.
+	TLSAudit *TLSAudit
+	// Code is the response code of checks other than http, such as NOERROR
+	// for dns or SERVING for grpc.
+	Code string
+	// RoundTrip is the time from sending a message to receiving its reply,
+	// for checks exchanging messages after connecting.
+	RoundTrip time.Duration
+	// Events is the number of events read by sse checks, FirstEvent the time
+	// to the first of them and MaxStreamGap the longest silence on the
+	// stream.
+	Events       int
+	FirstEvent   time.Duration
+	MaxStreamGap time.Duration
+}
+
+// TLSAudit is what a target accepts when handshaking with each TLS version
+// and cipher group.
+type TLSAudit struct {
+	// Versions and CipherGroups list what the target accepted, such as
+	// "TLS 1.2" and "3DES".
+	Versions     []string
+	CipherGroups []string
+	OCSPStapled  bool
+	// HSTS is the Strict-Transport-Security header of https targets.
+	HSTS string
+	// Findings describe weaknesses in words.
+	Findings []string
+}
+
+// Certificate states, by how close a certificate is to expiry.
+const (
+	CertOK       = "ok"
+	CertWarning  = "warning"
+	CertCritical = "critical"
+	CertExpired  = "expired"
+)
+
+// CertInfo describes the leaf certificate presented by a target.
+type CertInfo struct {
+	Subject  string
+	Issuer   string
+	NotAfter time.Time
+	DNSNames []string
+	// HostnameMatch is set when the certificate is valid for the target's
+	// host name.
+	HostnameMatch bool
+	// ChainValid is set when the chain verifies against the trusted roots;
+	// ChainError says why it does not.
+	ChainValid bool
+	ChainError string
+	// State is one of CertOK, CertWarning, CertCritical or CertExpired.
+	State string
+}
+
+// ExpiresIn returns how long the certificate stays valid after now.
+func (c *CertInfo) ExpiresIn(now time.Time) time.Duration {
+	return c.NotAfter.Sub(now)
+}
+
+// DNSAnswer is what a resolver answered to a dns check.
+type DNSAnswer struct {
+	// RCode is the response code, such as NOERROR or NXDOMAIN.
+	RCode string
+	// AnswerCount is the number of records in the answer section, of any
+	// type.
+	AnswerCount int
+	// Records are the sorted values of the records of the queried type.
+	Records []string
+	// Changed is set when Records differ from the previous answer, which is
+	// then kept in Previous.
+	Changed  bool
+	Previous []string
 }
 
 type URLStats struct {
@@ -22,6 +129,180 @@ type URLStats struct {
 	MaxPayload    int
 	TotalPayload  int
 	StatusCodes   map[int]int
+	InMaintenance bool
+
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	UpstreamDown         string
+	UpstreamDownCount    int
+	BodyTooLargeCount    int
+
+	TotalSchedulingLag time.Duration
+	MaxSchedulingLag   time.Duration
+	TotalLimiterDelay  time.Duration
+	MaxLimiterDelay    time.Duration
+
+	// SuccessCount counts eventual successes, FirstAttemptSuccessCount only
+	// those that needed no retry.
+	FirstAttemptSuccessCount int
+	TotalAttempts            int
+
+	// Request durations broken down by the kind of connection used.
+	NewConnections       int
+	TotalNewConnDuration time.Duration
+	TotalNewConnSetup    time.Duration
+	ReusedConnections    int
+	TotalReusedDuration  time.Duration
+
+	// Codes counts the response codes of checks other than http.
+	Codes map[string]int
+
+	// The latest answer of dns checks.
+	LastAnswerCount int
+	LastRecords     []string
+	AnswerChanges   int
+	LastChange      time.Time
+
+	// Message round trips of checks exchanging messages.
+	RoundTrips     int
+	TotalRoundTrip time.Duration
+
+	// Event streams of sse checks.
+	FirstEvents     int
+	TotalFirstEvent time.Duration
+	TotalEvents     int
+	MaxStreamGap    time.Duration
+
+	// Cert is the certificate seen by the latest probe that saw one.
+	Cert *CertInfo
+	// TLSAudit is the result of the latest tls-audit probe.
+	TLSAudit *TLSAudit
+	// Degraded is set while the latest probe was degraded.
+	Degraded      bool
+	DegradedCount int
+
+	// EffectiveInterval is the current time between probes, which adapts to
+	// recent results when the target has interval bounds.
+	EffectiveInterval time.Duration
+}
+
+// NewURLStats returns empty stats whose minimums are set by the first result.
+func NewURLStats(url string) *URLStats {
+	return &URLStats{
+		URL:         url,
+		StatusCodes: make(map[int]int),
+		MinDuration: time.Duration(^uint64(0) >> 1), // math.Maxint alternative (to avoid dependency on math package)
+		MinPayload:  int(^uint(0) >> 1),             // math.Maxint alternative (to avoid dependency on math package)
+	}
+}
+
+// Add folds a probe result into the stats.
+func (stats *URLStats) Add(result RequestResult) {
+	stats.TotalRequests++
+
+	stats.TotalAttempts += max(result.Attempts, 1)
+	if result.Success && result.Attempts <= 1 {
+		stats.FirstAttemptSuccessCount++
+	}
+
+	if result.Success {
+		stats.SuccessCount++
+		stats.ConsecutiveFailures = 0
+		stats.ConsecutiveSuccesses++
+	} else {
+		stats.ConsecutiveFailures++
+		stats.ConsecutiveSuccesses = 0
+	}
+
+	if result.BodyTooLarge {
+		stats.BodyTooLargeCount++
+	}
+
+	switch result.Connection {
+	case ConnectionNew:
+		stats.NewConnections++
+		stats.TotalNewConnDuration += result.Duration
+		stats.TotalNewConnSetup += result.ConnSetup
+	case ConnectionReused:
+		stats.ReusedConnections++
+		stats.TotalReusedDuration += result.Duration
+	}
+
+	stats.UpstreamDown = result.UpstreamDown
+	if result.UpstreamDown != "" {
+		stats.UpstreamDownCount++
+	}
+
+	if result.Duration < stats.MinDuration {
+		stats.MinDuration = result.Duration
+	}
+	if result.Duration > stats.MaxDuration {
+		stats.MaxDuration = result.Duration
+	}
+
+	stats.TotalDuration += result.Duration
+
+	stats.TotalLimiterDelay += result.LimiterDelay
+	if result.LimiterDelay > stats.MaxLimiterDelay {
+		stats.MaxLimiterDelay = result.LimiterDelay
+	}
+
+	stats.TotalSchedulingLag += result.SchedulingLag
+	if result.SchedulingLag > stats.MaxSchedulingLag {
+		stats.MaxSchedulingLag = result.SchedulingLag
+	}
+
+	if result.PayloadSize < stats.MinPayload {
+		stats.MinPayload = result.PayloadSize
+	}
+	if result.PayloadSize > stats.MaxPayload {
+		stats.MaxPayload = result.PayloadSize
+	}
+
+	stats.TotalPayload += result.PayloadSize
+
+	if result.Status > 0 {
+		stats.StatusCodes[result.Status]++
+	}
+
+	if result.RoundTrip > 0 {
+		stats.RoundTrips++
+		stats.TotalRoundTrip += result.RoundTrip
+	}
+
+	if result.Events > 0 {
+		stats.FirstEvents++
+		stats.TotalFirstEvent += result.FirstEvent
+		stats.TotalEvents += result.Events
+	}
+	stats.MaxStreamGap = max(stats.MaxStreamGap, result.MaxStreamGap)
+
+	stats.Degraded = result.Degraded
+	if result.Degraded {
+		stats.DegradedCount++
+	}
+	if result.Cert != nil {
+		stats.Cert = result.Cert
+	}
+	if result.TLSAudit != nil {
+		stats.TLSAudit = result.TLSAudit
+	}
+
+	if result.Code != "" {
+		if stats.Codes == nil {
+			stats.Codes = make(map[string]int)
+		}
+		stats.Codes[result.Code]++
+	}
+
+	if dns := result.DNS; dns != nil {
+		stats.LastAnswerCount = dns.AnswerCount
+		stats.LastRecords = dns.Records
+		if dns.Changed {
+			stats.AnswerChanges++
+			stats.LastChange = result.Timestamp
+		}
+	}
 }
 
 func (stats *URLStats) AvgDuration() time.Duration {
@@ -30,12 +311,65 @@ func (stats *URLStats) AvgDuration() time.Duration {
 	}
 	return stats.TotalDuration / time.Duration(stats.TotalRequests)
 }
+func (stats *URLStats) AvgSchedulingLag() time.Duration {
+	if stats.TotalRequests == 0 {
+		return 0
+	}
+	return stats.TotalSchedulingLag / time.Duration(stats.TotalRequests)
+}
+func (stats *URLStats) AvgLimiterDelay() time.Duration {
+	if stats.TotalRequests == 0 {
+		return 0
+	}
+	return stats.TotalLimiterDelay / time.Duration(stats.TotalRequests)
+}
+func (stats *URLStats) AvgNewConnDuration() time.Duration {
+	if stats.NewConnections == 0 {
+		return 0
+	}
+	return stats.TotalNewConnDuration / time.Duration(stats.NewConnections)
+}
+func (stats *URLStats) AvgNewConnSetup() time.Duration {
+	if stats.NewConnections == 0 {
+		return 0
+	}
+	return stats.TotalNewConnSetup / time.Duration(stats.NewConnections)
+}
+func (stats *URLStats) AvgReusedDuration() time.Duration {
+	if stats.ReusedConnections == 0 {
+		return 0
+	}
+	return stats.TotalReusedDuration / time.Duration(stats.ReusedConnections)
+}
+func (stats *URLStats) AvgRoundTrip() time.Duration {
+	if stats.RoundTrips == 0 {
+		return 0
+	}
+	return stats.TotalRoundTrip / time.Duration(stats.RoundTrips)
+}
+func (stats *URLStats) AvgFirstEvent() time.Duration {
+	if stats.FirstEvents == 0 {
+		return 0
+	}
+	return stats.TotalFirstEvent / time.Duration(stats.FirstEvents)
+}
 func (stats *URLStats) AvgPayload() int {
 	if stats.TotalRequests == 0 {
 		return 0
 	}
 	return stats.TotalPayload / stats.TotalRequests
 }
+func (stats *URLStats) FirstAttemptSuccessPercentage() int {
+	if stats.TotalRequests == 0 {
+		return 0
+	}
+	return int(100 * float32(stats.FirstAttemptSuccessCount) / float32(stats.TotalRequests))
+}
+
+func (stats *URLStats) IsDown() bool {
+	return stats.ConsecutiveFailures > 0
+}
+
 func (stats *URLStats) SuccessPercentage() int {
 	if stats.TotalRequests == 0 {
 		return 0
@@ -181,3 +181,85 @@ func TestURLStats_SuccessPercentage(t *testing.T) {
 		})
 	}
 }
+
+func TestURLStats_FirstAttemptSuccessPercentage(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    *URLStats
+		expected int
+	}{
+		// Test case for calculating first attempt success with no requests
+		// Verifies that zero is returned when there are no requests
+		{
+			name:     "zero requests",
+			stats:    &URLStats{},
+			expected: 0,
+		},
+		// Test case for probes that only succeeded after retries
+		// Verifies that the first attempt rate is lower than the eventual success rate
+		{
+			name: "retried successes",
+			stats: &URLStats{
+				TotalRequests:            4,
+				SuccessCount:             4,
+				FirstAttemptSuccessCount: 1,
+			},
+			expected: 25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.stats.FirstAttemptSuccessPercentage())
+		})
+	}
+}
+
+func TestURLStats_AddDNSAnswer(t *testing.T) {
+	// Test case for dns results folded into the stats
+	// Verifies that response codes are counted and answer changes are recorded
+	stats := NewURLStats("dns:///example.com")
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	stats.Add(RequestResult{Success: true, Code: "NOERROR", DNS: &DNSAnswer{RCode: "NOERROR", AnswerCount: 2, Records: []string{"192.0.2.1"}}})
+	stats.Add(RequestResult{Timestamp: at, Success: true, Code: "NOERROR", DNS: &DNSAnswer{RCode: "NOERROR", AnswerCount: 1, Records: []string{"192.0.2.9"}, Changed: true}})
+	stats.Add(RequestResult{Code: "SERVFAIL", DNS: &DNSAnswer{RCode: "SERVFAIL", Records: []string{}}})
+
+	assert.Equal(t, map[string]int{"NOERROR": 2, "SERVFAIL": 1}, stats.Codes)
+	assert.Equal(t, 0, stats.LastAnswerCount)
+	assert.Equal(t, 1, stats.AnswerChanges)
+	assert.Equal(t, at, stats.LastChange)
+	assert.Empty(t, stats.StatusCodes)
+}
+
+func TestURLStats_AddDegraded(t *testing.T) {
+	// Test case for degraded probes carrying certificate and audit details
+	// Verifies that the latest details are kept and degraded probes are counted
+	stats := NewURLStats("https://example.com")
+	audit := &TLSAudit{Versions: []string{"TLS 1.1"}, Findings: []string{"accepts TLS 1.1"}}
+	cert := &CertInfo{State: CertWarning}
+
+	stats.Add(RequestResult{Success: true, Degraded: true, Cert: cert, TLSAudit: audit})
+	assert.True(t, stats.Degraded)
+	stats.Add(RequestResult{Success: true})
+
+	assert.False(t, stats.Degraded)
+	assert.Equal(t, 1, stats.DegradedCount)
+	assert.Same(t, cert, stats.Cert)
+	assert.Same(t, audit, stats.TLSAudit)
+}
+
+func TestURLStats_AddEvents(t *testing.T) {
+	// Test case for sse probes, one of them reading no event
+	// Verifies that the time to the first event is averaged over probes that read one
+	stats := NewURLStats("https://example.com/events")
+
+	stats.Add(RequestResult{Success: true, Events: 3, FirstEvent: 100 * time.Millisecond, MaxStreamGap: 2 * time.Second})
+	stats.Add(RequestResult{Success: true, Events: 1, FirstEvent: 300 * time.Millisecond, MaxStreamGap: time.Second})
+	stats.Add(RequestResult{MaxStreamGap: 5 * time.Second})
+
+	assert.Equal(t, 2, stats.FirstEvents)
+	assert.Equal(t, 4, stats.TotalEvents)
+	assert.Equal(t, 200*time.Millisecond, stats.AvgFirstEvent())
+	assert.Equal(t, 5*time.Second, stats.MaxStreamGap)
+}
@@ -0,0 +1,142 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+// CurrentVersion is written into every snapshot. Bump it together with a new
+// entry in migrations whenever the stored format changes.
+const CurrentVersion = 1
+
+type Snapshot struct {
+	Version int                         `json:"version"`
+	SavedAt time.Time                   `json:"saved_at"`
+	Stats   map[string]*schema.URLStats `json:"stats"`
+}
+
+// migrations upgrades a raw snapshot from the keyed version to the next one.
+var migrations = map[int]func(raw map[string]json.RawMessage) error{}
+
+// Save writes the snapshot to a temporary file next to path and renames it
+// into place, so readers never observe a partially written file.
+func Save(path string, stats map[string]*schema.URLStats) error {
+	data, err := json.Marshal(Snapshot{
+		Version: CurrentVersion,
+		SavedAt: time.Now().UTC(),
+		Stats:   stats,
+	})
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() //nolint
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close() //nolint
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads a snapshot written by Save. A missing file is not an error and
+// yields no stats, so the first run with --state-file starts from scratch.
+func Load(path string) (map[string]*schema.URLStats, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	var version int
+	if err := json.Unmarshal(raw["version"], &version); err != nil {
+		return nil, fmt.Errorf("snapshot has no valid version: %w", err)
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("snapshot version %d is newer than supported version %d", version, CurrentVersion)
+	}
+	for ; version < CurrentVersion; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from snapshot version %d", version)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("migrate snapshot from version %d: %w", version, err)
+		}
+	}
+
+	stats := make(map[string]*schema.URLStats)
+	if err := json.Unmarshal(raw["stats"], &stats); err != nil {
+		return nil, fmt.Errorf("decode snapshot stats: %w", err)
+	}
+	for url, s := range stats {
+		s.URL = url
+		if s.StatusCodes == nil {
+			s.StatusCodes = make(map[int]int)
+		}
+	}
+	return stats, nil
+}
+
+type Snapshotter struct {
+	path     string
+	interval time.Duration
+}
+
+func NewSnapshotter(path string, interval time.Duration) *Snapshotter {
+	return &Snapshotter{path: path, interval: interval}
+}
+
+func (s *Snapshotter) Path() string {
+	return s.path
+}
+
+// Run saves the stats returned by source every interval until ctx is done.
+func (s *Snapshotter) Run(ctx context.Context, source func() map[string]*schema.URLStats) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Save(source()); err != nil {
+				log.WithError(err).Error("failed to save stats snapshot")
+			}
+		}
+	}
+}
+
+func (s *Snapshotter) Save(stats map[string]*schema.URLStats) error {
+	return Save(s.path, stats)
+}
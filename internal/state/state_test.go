@@ -0,0 +1,153 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dvdk01/http-status-monitor/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleStats() map[string]*schema.URLStats {
+	return map[string]*schema.URLStats{
+		"https://example.com": {
+			URL:                 "https://example.com",
+			TotalRequests:       10,
+			SuccessCount:        8,
+			MinDuration:         10 * time.Millisecond,
+			MaxDuration:         time.Second,
+			TotalDuration:       3 * time.Second,
+			MinPayload:          100,
+			MaxPayload:          400,
+			TotalPayload:        2000,
+			StatusCodes:         map[int]int{200: 8, 503: 2},
+			ConsecutiveFailures: 1,
+			UpstreamDownCount:   2,
+		},
+	}
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	// Test case for saving and reloading a snapshot
+	// Verifies that every cumulative counter survives the round trip
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	require.NoError(t, Save(path, sampleStats()))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, sampleStats(), loaded)
+}
+
+func TestSave_Atomic(t *testing.T) {
+	// Test case for overwriting an existing snapshot
+	// Verifies that the file is replaced and no temporary files are left behind
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	require.NoError(t, Save(path, map[string]*schema.URLStats{}))
+	require.NoError(t, Save(path, sampleStats()))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, loaded, 1)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+		wantLen int
+	}{
+		// Test case for a snapshot written by this version
+		// Verifies that a current snapshot is accepted
+		{
+			name:    "current version",
+			content: `{"version": 1, "stats": {"https://a.com": {"TotalRequests": 3}}}`,
+			wantLen: 1,
+		},
+		// Test case for a snapshot from a newer release
+		// Verifies that unknown future versions are refused instead of misread
+		{
+			name:    "future version",
+			content: `{"version": 99, "stats": {}}`,
+			wantErr: true,
+		},
+		// Test case for a snapshot without a version field
+		// Verifies that unversioned files are rejected
+		{
+			name:    "missing version",
+			content: `{"stats": {}}`,
+			wantErr: true,
+		},
+		// Test case for a corrupted snapshot
+		// Verifies that invalid JSON is reported as an error
+		{
+			name:    "corrupted",
+			content: `{"version": 1, "stats": `,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			stats, err := Load(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, stats, tt.wantLen)
+			for url, s := range stats {
+				assert.Equal(t, url, s.URL)
+				assert.NotNil(t, s.StatusCodes)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	// Test case for the first start with a state file configured
+	// Verifies that a missing file yields no stats and no error
+	stats, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Nil(t, stats)
+}
+
+func TestSnapshotter_Run(t *testing.T) {
+	// Test case for periodic snapshots
+	// Verifies that the snapshotter writes the current stats on every interval
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewSnapshotter(path, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, sampleStats)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 10, loaded["https://example.com"].TotalRequests)
+}
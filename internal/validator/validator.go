@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"fmt"
 	"net/url"
 	"strings"
 
@@ -9,31 +10,67 @@ import (
 
 type URLValidator struct {
 	validate *validator.Validate
+	rules    map[string]SchemeRule
+}
+
+// SchemeRule holds the validation tags applied to URLs of one scheme: URL to
+// the whole URL, Host to its host part and Path to its path. Empty tags
+// check nothing.
+type SchemeRule struct {
+	URL  string
+	Host string
+	Path string
+}
+
+// DefaultRules are the schemes accepted by NewURLValidator.
+var DefaultRules = map[string]SchemeRule{
+	"http":  {URL: "url"},
+	"https": {URL: "url"},
+	"tcp":   {Host: "hostname_port"},
+	"dns":   {Host: "omitempty,hostname_port|ip|hostname"},
+	"tls":   {Host: "hostname_port"},
+	"grpc":  {Host: "hostname_port"},
+	"ws":    {URL: "url"},
+	"wss":   {URL: "url"},
+	// unix:///path/app.sock:/healthz names the socket in the path.
+	"unix": {Host: "isdefault", Path: "required,startswith=/"},
 }
 
 func NewURLValidator() *URLValidator {
-	v := validator.New()
-	v.RegisterValidation("http_protocol", validateHTTPProtocol) //nolint:errcheck
 	return &URLValidator{
-		validate: v,
+		validate: validator.New(),
+		rules:    DefaultRules,
 	}
 }
 
-func validateHTTPProtocol(fl validator.FieldLevel) bool {
-	urlStr := fl.Field().String()
-	parsedURL, err := url.Parse(urlStr)
+func (v *URLValidator) ValidateURL(rawURL string) error {
+	if err := v.validate.Var(rawURL, "required"); err != nil {
+		return err
+	}
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return false
+		return err
 	}
-	return strings.HasPrefix(parsedURL.Scheme, "http")
-}
-
-func (v *URLValidator) ValidateURL(url string) error {
-	type urlStruct struct {
-		URL string `validate:"required,url,http_protocol"`
+	rule, ok := v.rules[strings.ToLower(parsedURL.Scheme)]
+	if !ok {
+		return fmt.Errorf("unsupported scheme %q", parsedURL.Scheme)
 	}
-
-	return v.validate.Struct(urlStruct{URL: url})
+	if rule.URL != "" {
+		if err := v.validate.Var(rawURL, rule.URL); err != nil {
+			return err
+		}
+	}
+	if rule.Host != "" {
+		if err := v.validate.Var(parsedURL.Host, rule.Host); err != nil {
+			return fmt.Errorf("host %q: %w", parsedURL.Host, err)
+		}
+	}
+	if rule.Path != "" {
+		if err := v.validate.Var(parsedURL.Path, rule.Path); err != nil {
+			return fmt.Errorf("path %q: %w", parsedURL.Path, err)
+		}
+	}
+	return nil
 }
 
 func (v *URLValidator) ValidateURLs(urls []string) ValidationResults {
@@ -54,6 +54,69 @@ func TestURLValidator_ValidateURL(t *testing.T) {
 			url:     "ftp://example.com",
 			wantErr: true,
 		},
+		// Test case for validating a TCP target with a port
+		// Verifies that tcp URLs naming a host and port are accepted
+		{
+			name:    "valid tcp url",
+			url:     "tcp://db.example.com:5432",
+			wantErr: false,
+		},
+		// Test case for validating a TCP target without a port
+		// Verifies that tcp URLs must name the port to connect to
+		{
+			name:    "invalid tcp url - missing port",
+			url:     "tcp://db.example.com",
+			wantErr: true,
+		},
+		// Test case for validating a DNS target with a resolver
+		// Verifies that dns URLs may name the resolver with or without a port
+		{
+			name:    "valid dns url with resolver",
+			url:     "dns://1.1.1.1/example.com?type=MX",
+			wantErr: false,
+		},
+		// Test case for validating a DNS target without a resolver
+		// Verifies that the resolver may be left to the config file
+		{
+			name:    "valid dns url without resolver",
+			url:     "dns:///example.com",
+			wantErr: false,
+		},
+		// Test case for validating a TLS target
+		// Verifies that tls URLs naming a host and port are accepted
+		{
+			name:    "valid tls url",
+			url:     "tls://mail.example.com:465",
+			wantErr: false,
+		},
+		// Test case for validating a secure WebSocket target
+		// Verifies that wss URLs with a path are accepted
+		{
+			name:    "valid wss url",
+			url:     "wss://rt.example.com/socket",
+			wantErr: false,
+		},
+		// Test case for validating a gRPC target with a service name
+		// Verifies that grpc URLs naming a host, port and service are accepted
+		{
+			name:    "valid grpc url",
+			url:     "grpc://orders.internal:50051/orders.v1.Orders",
+			wantErr: false,
+		},
+		// Test case for validating an HTTP target on a unix socket
+		// Verifies that the socket path and HTTP path form is accepted
+		{
+			name:    "valid unix url",
+			url:     "unix:///var/run/app.sock:/healthz",
+			wantErr: false,
+		},
+		// Test case for validating a unix URL with a host
+		// Verifies that unix sockets must be given as absolute paths
+		{
+			name:    "invalid unix url - host",
+			url:     "unix://var/run/app.sock:/healthz",
+			wantErr: true,
+		},
 		// Test case for validating an empty URL
 		// Verifies that empty strings are rejected as invalid URLs
 		{